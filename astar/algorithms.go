@@ -0,0 +1,259 @@
+package astar
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// This file holds the search algorithms other than A*.  They all search
+// the same kind of Space as SearchContext, honor the same Options, and
+// return the same errors.
+
+// BFS returns the path from start to a goal state with the fewest steps,
+// ignoring the cost of each step.
+func BFS[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	space = withGoal(space, opts)
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[S](opts.Observers)
+
+	tree := searchTree[S]{}
+	queue := []int{tree.add(start, -1, 0)}
+	visited := map[K]bool{space.Key(start): true}
+	result.frontier(len(queue))
+
+	cut := false // true if any path was cut off by opts.MaxDepth
+	for ; len(queue) > 0; queue = queue[1:] {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		n := queue[0]
+		node := tree[n]
+		if space.Done(node.state) {
+			path = tree.path(n)
+			result.found(node.cost, node.depth, math.Inf(1))
+			obs.goal(path, node.cost)
+			return
+		}
+		if opts.MaxDepth > 0 && node.depth >= opts.MaxDepth {
+			cut = true
+			continue
+		}
+		if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+			err = ErrMaxExpanded
+			return
+		}
+
+		result.Expanded += 1
+		obs.expand(node.state, node.cost)
+		for _, next := range space.Successors(node.state) {
+			result.Generated += 1
+			cost := node.cost + next.Cost
+			key := space.Key(next.State)
+			if visited[key] {
+				result.Duplicates += 1
+				obs.prune(next.State, node.state, cost)
+				continue
+			}
+			visited[key] = true
+			obs.enqueue(next.State, node.state, cost)
+			queue = append(queue, tree.add(next.State, n, cost))
+		}
+		result.frontier(len(queue) - 1)
+	}
+	if cut {
+		err = ErrMaxDepth
+	} else {
+		err = ErrNoPath
+	}
+	return
+}
+
+// Dijkstra returns the cheapest path from start to a goal state, using a
+// uniform-cost search that ignores the space's heuristic.
+func Dijkstra[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) (path []S, err error) {
+	return SearchContext(ctx, uniformCost[S, K]{space}, start, opts)
+}
+
+// uniformCost is a Space whose heuristic is always zero.
+type uniformCost[S any, K comparable] struct {
+	Space[S, K]
+}
+
+func (uniformCost[S, K]) Heuristic(s S) int { return 0 }
+
+// DFS returns the first path from start to a goal state found by a
+// depth-first search.  The path is not necessarily the shortest one.  The
+// search never revisits a state already on the current path, but may
+// revisit states reached by other paths, so opts.MaxDepth should be set
+// for all but the smallest spaces.  The frontier reported in opts.Result
+// is the longest path explored.
+func DFS[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	space = withGoal(space, opts)
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[S](opts.Observers)
+
+	path = []S{start}
+	onPath := map[K]bool{space.Key(start): true}
+	cut := false // true if any path was cut off by opts.MaxDepth
+
+	// visit extends path depth-first, and reports whether a goal was found.
+	var visit func(cost int) (bool, error)
+	visit = func(cost int) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		result.frontier(len(path))
+		state := path[len(path)-1]
+		if space.Done(state) {
+			result.found(cost, len(path)-1, math.Inf(1))
+			obs.goal(path, cost)
+			return true, nil
+		}
+		if opts.MaxDepth > 0 && len(path)-1 >= opts.MaxDepth {
+			cut = true
+			return false, nil
+		}
+		if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+			return false, ErrMaxExpanded
+		}
+
+		result.Expanded += 1
+		obs.expand(state, cost)
+		for _, next := range space.Successors(state) {
+			result.Generated += 1
+			key := space.Key(next.State)
+			if onPath[key] {
+				result.Duplicates += 1
+				obs.prune(next.State, state, cost+next.Cost)
+				continue
+			}
+			onPath[key] = true
+			obs.enqueue(next.State, state, cost+next.Cost)
+			path = append(path, next.State)
+			if found, err := visit(cost + next.Cost); found || err != nil {
+				return found, err
+			}
+			path = path[:len(path)-1]
+			delete(onPath, key)
+		}
+		return false, nil
+	}
+
+	found, err := visit(0)
+	if !found {
+		path = nil
+		if err == nil && cut {
+			err = ErrMaxDepth
+		} else if err == nil {
+			err = ErrNoPath
+		}
+	}
+	return
+}
+
+// IDAStar returns the cheapest path from start to a goal state, using
+// iterative-deepening A*.  Each iteration is a depth-first search that
+// abandons paths whose estimated cost g + h exceeds a threshold, which is
+// raised to the smallest estimate that exceeded it for the next iteration.
+// Only the current path is kept in memory, at the price of expanding
+// states again in every iteration.  As with SearchContext, the path is
+// only guaranteed to be the cheapest if the heuristic is admissible.  The
+// statistics in opts.Result are totals over all the iterations, and the
+// frontier reported is the longest path explored.
+func IDAStar[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	space = withGoal(space, opts)
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[S](opts.Observers)
+
+	path = []S{start}
+	onPath := map[K]bool{space.Key(start): true}
+	cut := false // true if any path was cut off by opts.MaxDepth
+
+	// visit extends path depth-first, as long as the estimated cost stays
+	// within threshold.  It reports whether a goal was found, and
+	// otherwise the smallest estimate that exceeded the threshold.
+	var visit func(cost, threshold int) (bool, int, error)
+	visit = func(cost, threshold int) (bool, int, error) {
+		if err := ctx.Err(); err != nil {
+			return false, 0, err
+		}
+		result.frontier(len(path))
+		state := path[len(path)-1]
+		if f := cost + space.Heuristic(state); f > threshold {
+			return false, f, nil
+		}
+		if space.Done(state) {
+			result.found(cost, len(path)-1, 1)
+			obs.goal(path, cost)
+			return true, 0, nil
+		}
+		if opts.MaxDepth > 0 && len(path)-1 >= opts.MaxDepth {
+			cut = true
+			return false, math.MaxInt, nil
+		}
+		if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+			return false, 0, ErrMaxExpanded
+		}
+
+		result.Expanded += 1
+		obs.expand(state, cost)
+		next := math.MaxInt
+		for _, succ := range space.Successors(state) {
+			result.Generated += 1
+			key := space.Key(succ.State)
+			if onPath[key] {
+				result.Duplicates += 1
+				obs.prune(succ.State, state, cost+succ.Cost)
+				continue
+			}
+			onPath[key] = true
+			obs.enqueue(succ.State, state, cost+succ.Cost)
+			path = append(path, succ.State)
+			found, f, err := visit(cost+succ.Cost, threshold)
+			if found || err != nil {
+				return found, 0, err
+			}
+			path = path[:len(path)-1]
+			delete(onPath, key)
+			next = min(next, f)
+		}
+		return false, next, nil
+	}
+
+	for threshold := space.Heuristic(start); ; {
+		found, next, err := visit(0, threshold)
+		if found {
+			return path, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next == math.MaxInt {
+			if cut {
+				return nil, ErrMaxDepth
+			}
+			return nil, ErrNoPath
+		}
+		threshold = next
+	}
+}
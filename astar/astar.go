@@ -2,103 +2,496 @@ package astar
 
 import (
 	"container/heap"
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
 )
 
-type SearchState interface {
-	String() string
-	Hash() string
-	AstarNextStates(visited map[string]bool) []SearchState
-	Heuristic() int
-	Done() bool
+var (
+	// ErrNoPath is returned when the search space is exhausted without
+	// reaching a goal state.
+	ErrNoPath = errors.New("astar: no path to a goal state")
+
+	// ErrMaxExpanded is returned when the search gives up after expanding
+	// Options.MaxExpanded states.
+	ErrMaxExpanded = errors.New("astar: expansion limit reached")
+
+	// ErrMaxDepth is returned when no goal state could be reached within
+	// Options.MaxDepth steps, but some paths were cut off at that depth.
+	ErrMaxDepth = errors.New("astar: no path within depth limit")
+)
+
+// Successor is a state that can be reached in a single step, along with
+// the cost of that step.
+type Successor[S any] struct {
+	State S
+	Cost  int
+}
+
+// Space describes a state space to be searched.  S is the type of the
+// states, and K is the type of the key that identifies a state.  Two
+// states with the same key are treated as the same state, so keys should
+// be cheap to compute and compare: a packed integer or a fixed-size array
+// is better than a string.
+type Space[S any, K comparable] interface {
+	Key(s S) K
+	Successors(s S) []Successor[S]
+	Heuristic(s S) int
+	Done(s S) bool
 }
 
-type SearchItem struct {
-	priority int // lower priorities are considered first
-	state    SearchState
-	history  []SearchState
+// Options holds optional limits on a search of states of type S.  A zero
+// value means "no limit".
+type Options[S any] struct {
+	MaxExpanded int // maximum number of states to expand
+	MaxDepth    int // maximum number of steps in a path
+
+	// Result, if not nil, is filled in with statistics about the search
+	// when it returns, whether or not a path was found.
+	Result *SearchResult
+
+	// Observers are notified of each event of the search, in order.
+	Observers []Observer[S]
+
+	// Goal, if not nil, decides which states are goals, in place of the
+	// space's Done method, so that a space can be searched for different
+	// targets.  The space's heuristic should still estimate the cost of
+	// reaching a goal; see Targets for a space that does both.  It is not
+	// used by Bidirectional, which is given its goals.
+	Goal func(S) bool
+
+	// Tie decides which of the states with the same priority
+	// SearchContext, Dijkstra and the iterators expand first, after
+	// Compare, if it is set.  The default is FIFO.  Either way, a search
+	// with the same options, and an ExactSet or LRUSet, always expands the
+	// same states in the same order, and finds the same path.  A BloomSet
+	// hashes with a new random seed for each search, so which states it
+	// wrongly drops, and so the path found, may differ from run to run.
+	Tie TieBreak
+
+	// Compare, if not nil, orders states with the same priority: it
+	// returns a negative number if a should be expanded before b, a
+	// positive number if b should be expanded first, and 0 to leave the
+	// decision to Tie.
+	Compare func(a, b S) int
+
+	// Visited chooses how the states reached are remembered by
+	// SearchContext, Dijkstra and the iterators.  It is an ExactSet if
+	// nil.  The other sets use less memory, but may make the search
+	// slower, or lose its guarantees.
+	Visited VisitedSet
+
+	// Checkpoint, if not empty, is the name of a file to which
+	// SearchContext saves its progress every CheckpointInterval (or every
+	// minute, if that is zero), and when ctx is cancelled, so that the
+	// search can be carried on by Resume after a crash.  States and keys
+	// are saved with encoding/gob, so a state type with unexported
+	// fields, like rtg.State, needs to implement encoding.BinaryMarshaler
+	// and encoding.BinaryUnmarshaler, and if S is an interface type, such
+	// as SearchState, the types that implement it need to be registered
+	// with gob.Register.  Only a search with an ExactSet can be
+	// checkpointed: with any other Visited set, SearchContext returns an
+	// error before it starts.  A checkpoint that cannot be saved ends the search
+	// with an error.
+	Checkpoint         string
+	CheckpointInterval time.Duration
+
+	// The remaining options trade optimality for speed.  They are only
+	// used by SearchContext.
+
+	// Weight, if greater than 1, makes the search greedier by inflating
+	// the heuristic, so that states are expanded in order of g + Weight*h.
+	// A path is usually found much sooner, and costs at most Weight times
+	// as much as the cheapest one, if the heuristic is admissible.
+	Weight float64
+
+	// BeamWidth, if positive, limits the frontier to that many states, by
+	// dropping the least promising ones.  This bounds the memory used, but
+	// the search may then miss the cheapest path, or find no path at all.
+	BeamWidth int
+
+	// Anytime, if not nil, makes the search carry on after it reaches a
+	// goal, looking for cheaper paths until none can exist, or until it
+	// is stopped by ctx or a limit.  Each path found that is cheaper than
+	// the last one is sent on Anytime, and the best one is returned, with
+	// a nil error.  Anytime is closed when the search returns.  It is best
+	// combined with a Weight, so that a first path is found quickly.
+	Anytime chan<- Solution[S]
+}
+
+// TieBreak chooses the order in which states with the same priority are
+// expanded.
+type TieBreak int
+
+const (
+	FIFO    TieBreak = iota // the state queued first is expanded first
+	LIFO                    // the state queued last is expanded first
+	LowestH                 // the state with the lowest heuristic, then FIFO
+)
+
+// searchNode is a node of the search tree.  The nodes are kept in a slice,
+// and each one refers to its parent by its position in that slice, so the
+// path to a node is only built when it is needed.
+type searchNode[S any] struct {
+	state  S
+	parent int // index of the parent node, or -1 for the start state
+	depth  int // number of steps from the start state
+	cost   int // cost of the path from the start state
+}
+
+// searchTree holds all the nodes generated by a search.
+type searchTree[S any] []searchNode[S]
+
+// add appends a node for state, reached from parent at the given total
+// cost, to the tree, and returns its index.
+func (t *searchTree[S]) add(state S, parent int, cost int) int {
+	depth := 0
+	if parent >= 0 {
+		depth = (*t)[parent].depth + 1
+	}
+	*t = append(*t, searchNode[S]{state, parent, depth, cost})
+	return len(*t) - 1
+}
+
+// path returns the states on the path from the start state to node n.
+func (t searchTree[S]) path(n int) []S {
+	path := make([]S, t[n].depth+1)
+	for ; n >= 0; n = t[n].parent {
+		path[t[n].depth] = t[n].state
+	}
+	return path
+}
+
+type SearchItem[S any] struct {
+	priority float64 // lower priorities are considered first
+	cost     int     // cost of the path to this state
+	node     int     // index of the state's node in the search tree
+	h        int     // heuristic of the state, for breaking ties
+	seq      int     // number of items queued before this one
 	index    int
 }
 
-type SearchQueue []*SearchItem
+// SearchQueue is a heap of SearchItems, with the lowest priority on top.
+// tie, if not nil, decides which of two items with the same priority comes
+// first.
+type SearchQueue[S any] struct {
+	items []*SearchItem[S]
+	tie   func(a, b *SearchItem[S]) bool
+}
 
-func (q SearchQueue) Len() int { return len(q) }
+func (q SearchQueue[S]) Len() int { return len(q.items) }
 
-func (q SearchQueue) Less(i, j int) bool {
-	return q[i].priority < q[j].priority
+func (q SearchQueue[S]) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.priority != b.priority || q.tie == nil {
+		return a.priority < b.priority
+	}
+	return q.tie(a, b)
 }
 
-func (q SearchQueue) Swap(i, j int) {
-	q[i], q[j] = q[j], q[i]
-	q[i].index = i
-	q[j].index = j
+func (q SearchQueue[S]) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
 }
 
-func (q *SearchQueue) Push(x interface{}) {
-	n := len(*q)
-	item := x.(*SearchItem)
+func (q *SearchQueue[S]) Push(x interface{}) {
+	n := len(q.items)
+	item := x.(*SearchItem[S])
 	item.index = n
-	*q = append(*q, item)
+	q.items = append(q.items, item)
 }
 
-func (q *SearchQueue) Pop() interface{} {
-	old := *q
+func (q *SearchQueue[S]) Pop() interface{} {
+	old := q.items
 	n := len(old)
 	item := old[n-1]
 	item.index = -1 // for safety
-	*q = old[0 : n-1]
+	q.items = old[0 : n-1]
 	return item
 }
 
-func Search(initState SearchState) (shortestPath []SearchState, err error) {
-	queue := SearchQueue{}
-	heap.Init(&queue)
+// top returns the item with the lowest priority, which must exist.
+func (q SearchQueue[S]) top() *SearchItem[S] { return q.items[0] }
+
+// Search returns the cheapest path from start to a goal state of space,
+// with no limits on the search.
+func Search[S any, K comparable](space Space[S, K], start S) (shortestPath []S, err error) {
+	return SearchContext(context.Background(), space, start, nil)
+}
+
+// SearchContext returns the cheapest path from start to a goal state of
+// space.  The search stops with an error if ctx is cancelled, if one of the
+// limits in opts is exceeded, or if there are no more states to explore.
+// opts may be nil.
+func SearchContext[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) (shortestPath []S, err error) {
 
-	item := &SearchItem{priority: initState.Heuristic(),
-		state:   initState,
-		history: []SearchState{}}
-	heap.Push(&queue, item)
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	e := newEngine(ctx, space, start, opts)
+	defer e.result.finish(e.started)
+	if _, exact := e.visited.(*exactSet[K]); opts.Checkpoint != "" && !exact {
+		if opts.Anytime != nil {
+			close(opts.Anytime)
+		}
+		return nil, errNotExact
+	}
+	return e.run()
+}
 
-	visited := make(map[string]bool, 10)
-	visited[initState.Hash()] = true
+// run carries on the search until it reaches a goal or stops, and returns
+// what SearchContext returns.
+func (e *engine[S, K]) run() (shortestPath []S, err error) {
+	opts, space, result := e.opts, e.space, e.result
+	if opts.Anytime != nil {
+		defer close(opts.Anytime)
+	}
 
-	count := 0 // number of states expanded
 	for {
-		item := heap.Pop(&queue).(*SearchItem)
-		if err != nil {
+		var n int
+		if n, err = e.next(); err != nil {
 			break
 		}
-		state := item.state
+		node := e.tree[n]
+		if e.incumbent >= 0 && node.cost+space.Heuristic(node.state) >= e.best {
+			// this state cannot lead to a cheaper goal
+			continue
+		}
 
-		// Add current state to history before generating next steps
-		nsteps := len(item.history)
-		path := make([]SearchState, nsteps+1)
-		for i, pastState := range item.history {
-			path[i] = pastState
+		if space.Done(node.state) {
+			if opts.Anytime == nil {
+				shortestPath = e.tree.path(n)
+				result.found(node.cost, node.depth, e.bound(node.cost))
+				e.obs.goal(shortestPath, node.cost)
+				return
+			}
+			e.incumbent, e.best = n, node.cost
+			solution := Solution[S]{e.tree.path(n), node.cost, e.bound(node.cost)}
+			select {
+			case opts.Anytime <- solution:
+			case <-e.ctx.Done():
+			}
+			continue
 		}
-		path[nsteps] = state
 
-		if state.Done() {
-			shortestPath = path
+		if err = e.expand(n); err != nil {
 			break
 		}
+		if err = e.checkpoint(false); err != nil {
+			break
+		}
+	}
 
-		count += 1
-		for _, newState := range state.AstarNextStates(visited) {
-			score := len(path) + newState.Heuristic()
-			item = &SearchItem{priority: score,
-				state:   newState,
-				history: path}
-			heap.Push(&queue, item)
+	if e.ctx.Err() != nil && errors.Is(err, e.ctx.Err()) {
+		// Save the search, so that it can be resumed where it stopped.
+		if cerr := e.checkpoint(true); cerr != nil {
+			err = errors.Join(err, cerr)
 		}
 	}
+	if e.incumbent >= 0 {
+		// An anytime search returns the best path it found, however it
+		// ended.
+		err = nil
+		shortestPath = e.tree.path(e.incumbent)
+		result.found(e.best, len(shortestPath)-1, e.bound(e.best))
+		e.obs.goal(shortestPath, e.best)
+	}
 	return
 }
 
-func PrintPath(path []SearchState) {
-	for step, state := range path {
-		fmt.Printf("\nStep %d\n", step)
-		fmt.Println(state.String())
+// bound returns the most that a path of the given cost can cost relative
+// to the cheapest path, as long as the heuristic is admissible.  There is
+// no bound if the search may have dropped states, by the beam or as false
+// positives of a BloomSet.
+func (e *engine[S, K]) bound(cost int) float64 {
+	if _, bloom := e.visited.(*bloomSet[K]); e.dropped || bloom {
+		return math.Inf(1)
+	}
+	if e.opts.Anytime == nil {
+		return e.weight
+	}
+	// No path through the frontier costs less than the lowest unweighted
+	// estimate in it.
+	lowest := cost
+	for _, item := range e.queue.items {
+		node := e.tree[item.node]
+		lowest = min(lowest, node.cost+e.space.Heuristic(node.state))
+	}
+	if lowest >= cost {
+		return 1
+	}
+	if lowest <= 0 {
+		return math.Inf(1)
+	}
+	return min(e.weight, float64(cost)/float64(lowest))
+}
+
+// engine holds the state of an A* search.  SearchContext and the iterators
+// drive it, deciding what to do with the goal states it turns up.
+type engine[S any, K comparable] struct {
+	ctx     context.Context
+	space   Space[S, K]
+	opts    *Options[S]
+	result  *SearchResult
+	obs     observers[S]
+	weight  float64   // inflation of the heuristic, at least 1
+	started time.Time // when the search started
+	saved   time.Time // when the last checkpoint was saved
+
+	tree  searchTree[S]
+	queue SearchQueue[S]
+
+	visited visited[K] // the states reached, as chosen by opts.Visited
+
+	seq     int  // number of items queued so far
+	cut     bool // true if any path was cut off by opts.MaxDepth
+	dropped bool // true if the beam has dropped any states
+
+	// In anytime mode, incumbent is the node of the cheapest goal found
+	// so far, and best is its cost.
+	incumbent, best int
+}
+
+// newEngine returns an engine for a search of space from start, with the
+// start state queued and opts.Result cleared.  opts must not be nil.
+func newEngine[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) *engine[S, K] {
+
+	e := &engine[S, K]{
+		ctx:     ctx,
+		space:   withGoal(space, opts),
+		opts:    opts,
+		result:  opts.result(),
+		obs:     opts.Observers,
+		weight:  max(opts.Weight, 1),
+		visited: newVisited[K](opts.Visited),
+
+		incumbent: -1,
+		best:      math.MaxInt,
+	}
+	e.queue.tie = e.tieBreak()
+	e.visited.reach(space.Key(start), 0)
+	e.started = time.Now()
+	e.saved = e.started
+	e.push(e.tree.add(start, -1, 0))
+	e.result.frontier(e.queue.Len())
+	return e
+}
+
+// push queues node n of the tree.
+func (e *engine[S, K]) push(n int) {
+	node := e.tree[n]
+	h := e.space.Heuristic(node.state)
+	heap.Push(&e.queue, &SearchItem[S]{
+		priority: float64(node.cost) + e.weight*float64(h),
+		cost:     node.cost,
+		node:     n,
+		h:        h,
+		seq:      e.seq})
+	e.seq++
+}
+
+// tieBreak returns the function that orders items of the same priority,
+// as chosen by opts.Compare and opts.Tie.
+func (e *engine[S, K]) tieBreak() func(a, b *SearchItem[S]) bool {
+	tie := e.opts.Tie
+	compare := e.opts.Compare
+	return func(a, b *SearchItem[S]) bool {
+		if compare != nil {
+			if c := compare(e.tree[a.node].state, e.tree[b.node].state); c != 0 {
+				return c < 0
+			}
+		}
+		if tie == LowestH && a.h != b.h {
+			return a.h < b.h
+		}
+		if tie == LIFO {
+			return a.seq > b.seq
+		}
+		return a.seq < b.seq
+	}
+}
+
+// next takes the most promising node off the queue, and returns its index
+// in the tree.  States that have been expanded, or that a cheaper path has
+// been queued to since, are skipped.  It returns an error if ctx is
+// cancelled or there are no more states to explore.
+func (e *engine[S, K]) next() (n int, err error) {
+	for {
+		if err = e.ctx.Err(); err != nil {
+			return -1, err
+		}
+		if e.queue.Len() == 0 {
+			if e.cut {
+				return -1, ErrMaxDepth
+			}
+			return -1, ErrNoPath
+		}
+		item := heap.Pop(&e.queue).(*SearchItem[S])
+		key := e.space.Key(e.tree[item.node].state)
+		if e.visited.current(key, item.cost) {
+			return item.node, nil
+		}
+	}
+}
+
+// expand expands node n, queueing those of its successors that have not
+// been reached more cheaply already.  A node at opts.MaxDepth is not
+// expanded.  It returns ErrMaxExpanded if the expansion limit has been
+// reached.
+func (e *engine[S, K]) expand(n int) error {
+	node := e.tree[n]
+	if e.opts.MaxDepth > 0 && node.depth >= e.opts.MaxDepth {
+		e.cut = true
+		return nil
+	}
+	if e.opts.MaxExpanded > 0 && e.result.Expanded >= e.opts.MaxExpanded {
+		return ErrMaxExpanded
+	}
+
+	e.result.Expanded += 1
+	e.obs.expand(node.state, node.cost)
+	e.visited.expand(e.space.Key(node.state), node.cost)
+	for _, next := range e.space.Successors(node.state) {
+		e.result.Generated += 1
+		cost := node.cost + next.Cost
+		key := e.space.Key(next.State)
+		if !e.visited.reach(key, cost) {
+			e.result.Duplicates += 1
+			e.obs.prune(next.State, node.state, cost)
+			continue
+		}
+		e.obs.enqueue(next.State, node.state, cost)
+		e.push(e.tree.add(next.State, n, cost))
+	}
+	if e.opts.BeamWidth > 0 && e.queue.Len() > e.opts.BeamWidth {
+		// A sorted queue is still a heap.
+		sort.Sort(e.queue)
+		e.queue.items = e.queue.items[:e.opts.BeamWidth]
+		e.dropped = true
 	}
-	fmt.Println("** DONE **\n")
+	e.result.frontier(e.queue.Len())
+	return nil
+}
+
+// UnitCost returns the given states as successors that each cost 1 to
+// reach.
+func UnitCost[S any](states []S) []Successor[S] {
+	result := make([]Successor[S], len(states))
+	for i, state := range states {
+		result[i] = Successor[S]{state, 1}
+	}
+	return result
+}
+
+// PrintPath prints the states along path to standard output, as
+// RenderText does.
+func PrintPath[S fmt.Stringer](path []S) {
+	RenderText(os.Stdout, path)
 }
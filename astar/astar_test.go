@@ -1,9 +1,1267 @@
 package astar
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestNextStates(t *testing.T) {
-	t.Errorf("No tests have been written yet")
+// graph is a small directed graph used to exercise the search engine.
+// Node names map to their neighbors; h holds the heuristic for each node.
+type graph struct {
+	edges map[string][]string
+	h     map[string]int
+	goal  string
+}
+
+type graphState struct {
+	g    *graph
+	name string
+}
+
+func (s graphState) String() string { return s.name }
+func (s graphState) Hash() string   { return s.name }
+func (s graphState) Heuristic() int { return s.g.h[s.name] }
+func (s graphState) Done() bool     { return s.name == s.g.goal }
+
+func (s graphState) AstarNextStates() []SearchState {
+	states := []SearchState{}
+	for _, name := range s.g.edges[s.name] {
+		states = append(states, graphState{s.g, name})
+	}
+	return states
+}
+
+// line returns a graph a -> b -> c -> ... of the given length, whose goal
+// is the last node.
+func line(n int) *graph {
+	g := &graph{edges: map[string][]string{}, h: map[string]int{}}
+	for i := 0; i < n-1; i++ {
+		g.edges[string(rune('a'+i))] = []string{string(rune('a' + i + 1))}
+	}
+	g.goal = string(rune('a' + n - 1))
+	return g
+}
+
+// counter is an infinite chain of states that never reaches a goal.
+type counter int
+
+func (c counter) String() string { return "" }
+func (c counter) Hash() string   { return strconv.Itoa(int(c)) }
+func (c counter) Heuristic() int { return 0 }
+func (c counter) Done() bool     { return false }
+
+func (c counter) AstarNextStates() []SearchState {
+	return []SearchState{c + 1}
+}
+
+// weightedState is a graphState whose edges have costs.
+type weightedState struct {
+	graphState
+	cost map[string]int // edge costs, keyed by "from" + "to"
+}
+
+func (s weightedState) AstarSuccessors() []Successor[SearchState] {
+	result := []Successor[SearchState]{}
+	for _, name := range s.g.edges[s.name] {
+		result = append(result, Successor[SearchState]{
+			State: weightedState{graphState{s.g, name}, s.cost},
+			Cost:  s.cost[s.name+name]})
+	}
+	return result
+}
+
+func pathNames(path []SearchState) (names string) {
+	for _, state := range path {
+		names += state.String()
+	}
+	return
+}
+
+func TestSearch(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d"},
+			"c": {"e"},
+			"e": {"d"},
+		},
+		h:    map[string]int{"a": 2, "b": 1, "c": 2, "e": 1},
+		goal: "d",
+	}
+	path, err := Search[SearchState](States{}, graphState{g, "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := pathNames(path); names != "abd" {
+		t.Errorf("path %q  (expected %q)", names, "abd")
+	}
+}
+
+func TestSearchWeighted(t *testing.T) {
+	// a -> b -> d is shortest, but a -> c -> e -> d is cheaper
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d"},
+			"c": {"e"},
+			"e": {"d"},
+		},
+		h:    map[string]int{},
+		goal: "d",
+	}
+	cost := map[string]int{"ab": 1, "bd": 5, "ac": 1, "ce": 1, "ed": 1}
+	path, err := Search[SearchState](States{}, weightedState{graphState{g, "a"}, cost})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := pathNames(path); names != "aced" {
+		t.Errorf("path %q  (expected %q)", names, "aced")
+	}
+}
+
+func TestSearchReopen(t *testing.T) {
+	// The heuristic is admissible but not consistent, so b is first
+	// expanded via the expensive edge s -> b, and has to be re-opened when
+	// the cheaper path s -> a -> b is found.
+	g := &graph{
+		edges: map[string][]string{
+			"s": {"a", "b"},
+			"a": {"b"},
+			"b": {"g"},
+		},
+		h:    map[string]int{"a": 3},
+		goal: "g",
+	}
+	cost := map[string]int{"sa": 1, "sb": 3, "ab": 1, "bg": 3}
+	path, err := Search[SearchState](States{}, weightedState{graphState{g, "s"}, cost})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := pathNames(path); names != "sabg" {
+		t.Errorf("path %q  (expected %q)", names, "sabg")
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	noGoal := line(4)
+	noGoal.goal = "z"
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	cases := [...]struct {
+		name  string
+		ctx   context.Context
+		state SearchState
+		opts  *Options[SearchState]
+		err   error
+	}{
+		{"exhausted", context.Background(), graphState{noGoal, "a"}, nil, ErrNoPath},
+		{"expanded", context.Background(), counter(0), &Options[SearchState]{MaxExpanded: 100}, ErrMaxExpanded},
+		{"depth", context.Background(), graphState{line(5), "a"}, &Options[SearchState]{MaxDepth: 3}, ErrMaxDepth},
+		{"cancelled", cancelled, graphState{line(5), "a"}, nil, context.Canceled},
+		{"deadline", expired, counter(0), nil, context.DeadlineExceeded},
+	}
+
+	for _, item := range cases {
+		path, err := SearchContext(item.ctx, States{}, item.state, item.opts)
+		if !errors.Is(err, item.err) {
+			t.Errorf("[%s] error %v  (expected %v)", item.name, err, item.err)
+		}
+		if path != nil {
+			t.Errorf("[%s] unexpected path %q", item.name, pathNames(path))
+		}
+	}
+}
+
+func TestSearchDepthLimit(t *testing.T) {
+	path, err := SearchContext[SearchState](context.Background(), States{}, graphState{line(5), "a"},
+		&Options[SearchState]{MaxDepth: 4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := pathNames(path); names != "abcde" {
+		t.Errorf("path %q  (expected %q)", names, "abcde")
+	}
+}
+
+func TestSearchTreePath(t *testing.T) {
+	tree := searchTree[string]{}
+	a := tree.add("a", -1, 0)
+	b := tree.add("b", a, 1)
+	tree.add("c", a, 1)
+	d := tree.add("d", b, 2)
+
+	path := tree.path(d)
+	if len(path) != 3 || path[0] != "a" || path[1] != "b" || path[2] != "d" {
+		t.Errorf("path %v  (expected [a b d])", path)
+	}
+	if path := tree.path(a); len(path) != 1 || path[0] != "a" {
+		t.Errorf("path %v  (expected [a])", path)
+	}
+}
+
+type algorithm func(context.Context, Space[SearchState, string], SearchState,
+	*Options[SearchState]) ([]SearchState, error)
+
+var algorithms = map[string]algorithm{
+	"SearchContext": SearchContext[SearchState, string],
+	"BFS":           BFS[SearchState, string],
+	"Dijkstra":      Dijkstra[SearchState, string],
+	"DFS":           DFS[SearchState, string],
+	"IDAStar":       IDAStar[SearchState, string],
+	"ParallelSearch": func(ctx context.Context, space Space[SearchState, string],
+		start SearchState, opts *Options[SearchState]) ([]SearchState, error) {
+		return ParallelSearch(ctx, space, start, 4, opts)
+	},
+}
+
+func TestAlgorithms(t *testing.T) {
+	// a -> b -> d is shortest, but a -> c -> e -> d is cheaper
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d", "a"},
+			"c": {"e"},
+			"e": {"d", "c"},
+		},
+		h:    map[string]int{"a": 2, "b": 1, "c": 2, "e": 1},
+		goal: "d",
+	}
+	cost := map[string]int{"ab": 1, "ba": 1, "bd": 5, "ac": 1, "ce": 1,
+		"ec": 1, "ed": 1}
+	expected := map[string]string{
+		"SearchContext":  "aced",
+		"BFS":            "abd",
+		"Dijkstra":       "aced",
+		"DFS":            "abd",
+		"IDAStar":        "aced",
+		"ParallelSearch": "aced",
+	}
+
+	for name, search := range algorithms {
+		path, err := search(context.Background(), States{},
+			weightedState{graphState{g, "a"}, cost}, nil)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if names := pathNames(path); names != expected[name] {
+			t.Errorf("[%s] path %q  (expected %q)", name, names, expected[name])
+		}
+	}
+}
+
+func TestAlgorithmErrors(t *testing.T) {
+	noGoal := line(4)
+	noGoal.goal = "z"
+
+	cases := [...]struct {
+		name  string
+		state SearchState
+		opts  *Options[SearchState]
+		err   error
+	}{
+		{"exhausted", graphState{noGoal, "a"}, nil, ErrNoPath},
+		{"expanded", counter(0), &Options[SearchState]{MaxExpanded: 100}, ErrMaxExpanded},
+		{"depth", graphState{line(5), "a"}, &Options[SearchState]{MaxDepth: 3}, ErrMaxDepth},
+	}
+
+	for name, search := range algorithms {
+		for _, item := range cases {
+			path, err := search(context.Background(), States{}, item.state, item.opts)
+			if !errors.Is(err, item.err) {
+				t.Errorf("[%s %s] error %v  (expected %v)", name, item.name,
+					err, item.err)
+			}
+			if path != nil {
+				t.Errorf("[%s %s] unexpected path %q", name, item.name,
+					pathNames(path))
+			}
+		}
+	}
+}
+
+func TestBidirectional(t *testing.T) {
+	// A directed graph with two goals: the shortest path is a-c-f-h, and
+	// the search needs pred to follow the edges backward.
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d"},
+			"c": {"f"},
+			"d": {"e"},
+			"e": {"g"},
+			"f": {"h"},
+		},
+		h: map[string]int{},
+	}
+	reverse := map[string][]string{}
+	for from, tos := range g.edges {
+		for _, to := range tos {
+			reverse[to] = append(reverse[to], from)
+		}
+	}
+	pred := func(s SearchState) []Successor[SearchState] {
+		states := []SearchState{}
+		for _, name := range reverse[s.String()] {
+			states = append(states, graphState{g, name})
+		}
+		return UnitCost(states)
+	}
+	goals := []SearchState{graphState{g, "g"}, graphState{g, "h"}}
+
+	path, err := Bidirectional[SearchState](context.Background(), States{},
+		graphState{g, "a"}, goals, pred, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := pathNames(path); names != "acfh" {
+		t.Errorf("path %q  (expected %q)", names, "acfh")
+	}
+
+	path, err = Bidirectional[SearchState](context.Background(), States{},
+		graphState{g, "a"}, []SearchState{graphState{g, "z"}}, pred, nil)
+	if !errors.Is(err, ErrNoPath) || path != nil {
+		t.Errorf("path %q, error %v  (expected %v)", pathNames(path), err, ErrNoPath)
+	}
+}
+
+func TestSearchResult(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d", "a"},
+			"c": {"e"},
+			"e": {"d", "c"},
+		},
+		h:    map[string]int{},
+		goal: "d",
+	}
+	cost := map[string]int{"ab": 1, "ba": 1, "bd": 5, "ac": 1, "ce": 1,
+		"ec": 1, "ed": 1}
+
+	for name, search := range algorithms {
+		var result SearchResult
+		path, err := search(context.Background(), States{},
+			weightedState{graphState{g, "a"}, cost}, &Options[SearchState]{Result: &result})
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if result.Depth != len(path)-1 {
+			t.Errorf("[%s] depth %d  (expected %d)", name, result.Depth, len(path)-1)
+		}
+		pathCost := 0
+		for i := 1; i < len(path); i++ {
+			pathCost += cost[path[i-1].String()+path[i].String()]
+		}
+		if result.Cost != pathCost {
+			t.Errorf("[%s] cost %d  (expected %d)", name, result.Cost, pathCost)
+		}
+		if result.Expanded == 0 || result.Generated < result.Expanded ||
+			result.MaxFrontier == 0 {
+			t.Errorf("[%s] implausible statistics %+v", name, result)
+		}
+	}
+
+	var result SearchResult
+	_, err := SearchContext[SearchState](context.Background(), States{},
+		counter(0), &Options[SearchState]{MaxExpanded: 10, Result: &result})
+	if !errors.Is(err, ErrMaxExpanded) {
+		t.Errorf("error %v  (expected %v)", err, ErrMaxExpanded)
+	}
+	if result.Expanded != 10 || result.Cost != -1 || result.Depth != -1 {
+		t.Errorf("unexpected statistics %+v", result)
+	}
+}
+
+func TestBranchingFactor(t *testing.T) {
+	cases := [...]struct {
+		generated, depth int
+		expected         float64
+	}{
+		{6, 2, 2},   // 1 + 2 + 4
+		{39, 3, 3},  // 1 + 3 + 9 + 27
+		{5, 5, 1},   // 1 + 1 + 1 + 1 + 1 + 1
+		{10, 0, 0},  // no steps
+		{10, -1, 0}, // no path
+	}
+
+	for _, item := range cases {
+		r := SearchResult{Generated: item.generated, Depth: item.depth}
+		if b := r.BranchingFactor(); b < item.expected-1e-6 || b > item.expected+1e-6 {
+			t.Errorf("%d generated, depth %d -> %f  (expected %f)",
+				item.generated, item.depth, b, item.expected)
+		}
+	}
+}
+
+func TestObservers(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"d", "a"},
+			"c": {"e"},
+			"e": {"d", "c"},
+		},
+		h:    map[string]int{},
+		goal: "d",
+	}
+
+	for name, search := range algorithms {
+		var result SearchResult
+		expanded, enqueued, pruned := 0, 0, 0
+		goal := ""
+		hooks := Hooks[SearchState]{
+			OnExpand:  func(state SearchState, cost int) { expanded++ },
+			OnEnqueue: func(state, parent SearchState, cost int) { enqueued++ },
+			OnPrune:   func(state, parent SearchState, cost int) { pruned++ },
+			OnGoal:    func(path []SearchState, cost int) { goal = pathNames(path) },
+		}
+		opts := &Options[SearchState]{Result: &result,
+			Observers: []Observer[SearchState]{hooks}}
+		path, err := search(context.Background(), States{}, graphState{g, "a"}, opts)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if expanded != result.Expanded || pruned != result.Duplicates ||
+			enqueued+pruned != result.Generated {
+			t.Errorf("[%s] %d expanded, %d enqueued, %d pruned  (result %+v)",
+				name, expanded, enqueued, pruned, result)
+		}
+		if goal != pathNames(path) {
+			t.Errorf("[%s] goal path %q  (expected %q)", name, goal, pathNames(path))
+		}
+	}
+}
+
+func TestDotWriter(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"c"},
+		},
+		h:    map[string]int{},
+		goal: "c",
+	}
+	var buf bytes.Buffer
+	dot := NewDotWriter[SearchState](&buf)
+	_, err := BFS[SearchState](context.Background(), States{}, graphState{g, "a"},
+		&Options[SearchState]{Observers: []Observer[SearchState]{dot}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := dot.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, line := range []string{
+		"digraph search {",
+		`n0 [label="a\l"];`,
+		"n0 -> n1;",
+		"n0 -> n2;",
+		"n1 -> n2 [style=dashed];",
+		"n0 -> n2 [penwidth=3];",
+		"}",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("%q not found in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestCheckHeuristic(t *testing.T) {
+	// a -> b -> c -> d, with a shortcut a -> d that costs 5.
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "d"},
+			"b": {"c"},
+			"c": {"d"},
+		},
+		goal: "d",
+	}
+	cost := map[string]int{"ab": 1, "bc": 1, "cd": 1, "ad": 5}
+	cases := [...]struct {
+		name         string
+		h            map[string]int
+		limit        int
+		complete     bool
+		inadmissible string // states reported, in order
+		inconsistent string // steps reported, as from-to pairs
+	}{
+		{"exact", map[string]int{"a": 3, "b": 2, "c": 1}, 0, true, "", ""},
+		{"zero", map[string]int{}, 0, true, "", ""},
+		{"consistent", map[string]int{"a": 2, "b": 1, "c": 1}, 0, true, "", ""},
+		{"inconsistent", map[string]int{"a": 3, "b": 1, "c": 1}, 0, true, "", "ab"},
+		{"inadmissible", map[string]int{"a": 3, "b": 4, "c": 1}, 0, true, "b", "bc"},
+		{"goal", map[string]int{"d": 1}, 0, true, "d", ""},
+		{"limited", map[string]int{"a": 4, "b": 2, "c": 1}, 3, false, "", "ab"},
+	}
+
+	for _, item := range cases {
+		g.h = item.h
+		report, err := CheckHeuristic[SearchState](context.Background(), States{},
+			weightedState{graphState{g, "a"}, cost}, item.limit)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", item.name, err)
+			continue
+		}
+		if report.Complete != item.complete {
+			t.Errorf("[%s] complete %v  (expected %v)", item.name,
+				report.Complete, item.complete)
+		}
+		inadmissible := ""
+		for _, v := range report.Inadmissible {
+			inadmissible += v.State.String()
+		}
+		if inadmissible != item.inadmissible || report.Admissible() != (item.inadmissible == "") {
+			t.Errorf("[%s] inadmissible %q  (expected %q)", item.name,
+				inadmissible, item.inadmissible)
+		}
+		inconsistent := ""
+		for _, v := range report.Inconsistent {
+			inconsistent += v.From.String() + v.To.String()
+		}
+		if inconsistent != item.inconsistent || report.Consistent() != (item.inconsistent == "") {
+			t.Errorf("[%s] inconsistent %q  (expected %q)", item.name,
+				inconsistent, item.inconsistent)
+		}
+	}
+}
+
+func TestSuboptimalSearch(t *testing.T) {
+	// a -> b -> g is the greedy route, but a -> c -> d -> g is cheaper.
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"g"},
+			"c": {"d"},
+			"d": {"g"},
+		},
+		h:    map[string]int{"a": 6, "b": 1, "c": 4, "d": 2},
+		goal: "g",
+	}
+	cost := map[string]int{"ab": 1, "bg": 10, "ac": 2, "cd": 2, "dg": 2}
+	inf := math.Inf(1)
+	cases := [...]struct {
+		name  string
+		opts  Options[SearchState]
+		path  string
+		bound float64
+	}{
+		{"astar", Options[SearchState]{}, "acdg", 1},
+		{"weighted", Options[SearchState]{Weight: 5}, "abg", 5},
+		{"beam", Options[SearchState]{BeamWidth: 1}, "abg", inf},
+		{"wide beam", Options[SearchState]{BeamWidth: 2}, "acdg", 1},
+	}
+
+	for _, item := range cases {
+		var result SearchResult
+		item.opts.Result = &result
+		path, err := SearchContext[SearchState](context.Background(), States{},
+			weightedState{graphState{g, "a"}, cost}, &item.opts)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", item.name, err)
+			continue
+		}
+		if names := pathNames(path); names != item.path {
+			t.Errorf("[%s] path %q  (expected %q)", item.name, names, item.path)
+		}
+		if result.Bound != item.bound {
+			t.Errorf("[%s] bound %v  (expected %v)", item.name, result.Bound, item.bound)
+		}
+	}
+}
+
+func TestAnytimeSearch(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"a": {"b", "c"},
+			"b": {"g"},
+			"c": {"d"},
+			"d": {"g"},
+		},
+		h:    map[string]int{"a": 6, "b": 1, "c": 4, "d": 2},
+		goal: "g",
+	}
+	cost := map[string]int{"ab": 1, "bg": 10, "ac": 2, "cd": 2, "dg": 2}
+
+	solutions := make(chan Solution[SearchState])
+	var path []SearchState
+	var err error
+	done := make(chan bool)
+	go func() {
+		path, err = SearchContext[SearchState](context.Background(), States{},
+			weightedState{graphState{g, "a"}, cost},
+			&Options[SearchState]{Weight: 5, Anytime: solutions})
+		close(done)
+	}()
+
+	expected := [...]struct {
+		path  string
+		cost  int
+		bound float64
+	}{
+		{"abg", 11, 11.0 / 6},
+		{"acdg", 6, 1},
+	}
+	n := 0
+	for solution := range solutions {
+		if n >= len(expected) {
+			t.Errorf("unexpected solution %q", pathNames(solution.Path))
+			continue
+		}
+		item := expected[n]
+		if names := pathNames(solution.Path); names != item.path ||
+			solution.Cost != item.cost || solution.Bound != item.bound {
+			t.Errorf("solution %d: %q costs %d, bound %v  (expected %q, %d, %v)",
+				n, names, solution.Cost, solution.Bound, item.path, item.cost, item.bound)
+		}
+		n++
+	}
+	if n != len(expected) {
+		t.Errorf("%d solutions  (expected %d)", n, len(expected))
+	}
+	<-done
+	if err != nil || pathNames(path) != "acdg" {
+		t.Errorf("returned %q, error %v  (expected %q)", pathNames(path), err, "acdg")
+	}
+}
+
+// words is a Space of the strings of a's and b's, where appending an a
+// costs 1 and appending a b costs 2.  The strings of length n are goals.
+type words int
+
+func (n words) Key(s string) string    { return s }
+func (n words) Heuristic(s string) int { return n.Len() - len(s) }
+func (n words) Done(s string) bool     { return len(s) == n.Len() }
+func (n words) Len() int               { return int(n) }
+
+func (n words) Successors(s string) []Successor[string] {
+	if n.Done(s) {
+		return nil
+	}
+	return []Successor[string]{{s + "a", 1}, {s + "b", 2}}
+}
+
+func TestGoals(t *testing.T) {
+	var result SearchResult
+	found := []string{}
+	last := 0
+	for solution, err := range Goals(context.Background(), words(3), "",
+		&Options[string]{Result: &result}) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		goal := solution.Path[len(solution.Path)-1]
+		if solution.Cost < last {
+			t.Errorf("%q costs %d, after a path costing %d", goal, solution.Cost, last)
+		}
+		if len(solution.Path) != 4 || solution.Path[0] != "" {
+			t.Errorf("%q has path %q", goal, solution.Path)
+		}
+		last = solution.Cost
+		found = append(found, goal)
+	}
+	if len(found) != 8 || found[0] != "aaa" || found[7] != "bbb" {
+		t.Errorf("found goals %q  (expected all 8, from %q to %q)", found, "aaa", "bbb")
+	}
+	if result.Cost != 6 || result.Expanded != 7 {
+		t.Errorf("result %+v  (expected cost 6, 7 states expanded)", result)
+	}
+
+	// Stopping early leaves the rest of the space unexplored.
+	n := 0
+	for range Goals(context.Background(), words(10), "", &Options[string]{Result: &result}) {
+		if n++; n == 2 {
+			break
+		}
+	}
+	if result.Cost != 11 || result.Expanded >= 100 {
+		t.Errorf("after 2 goals, result %+v  (expected cost 11, few states expanded)", result)
+	}
+
+	// With a BloomSet, the goals come with no bound.
+	for solution, err := range Goals(context.Background(), words(3), "",
+		&Options[string]{Visited: BloomSet{}, Result: &result}) {
+		if err != nil || !math.IsInf(solution.Bound, 1) {
+			t.Errorf("solution bound %v, error %v  (expected +Inf)", solution.Bound, err)
+		}
+	}
+	if !math.IsInf(result.Bound, 1) {
+		t.Errorf("result bound %v  (expected +Inf)", result.Bound)
+	}
+}
+
+func TestGoalsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options[SearchState]
+		err  error
+	}{
+		{"expansions", &Options[SearchState]{MaxExpanded: 5}, ErrMaxExpanded},
+		{"depth", &Options[SearchState]{MaxDepth: 5}, nil},
+	}
+	for _, test := range tests {
+		n := 0
+		var err error
+		for solution, e := range Goals[SearchState](context.Background(), States{},
+			counter(0), test.opts) {
+			if e == nil {
+				t.Errorf("%s: unexpected path %v", test.name, solution.Path)
+			}
+			err = e
+			n++
+		}
+		if err != test.err || n > 1 {
+			t.Errorf("%s: %d values, error %v  (expected %v)", test.name, n, err, test.err)
+		}
+	}
+}
+
+func TestExpansions(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}},
+		h:     map[string]int{"a": 2, "b": 1, "c": 1},
+		goal:  "d",
+	}
+	visited := ""
+	for visit, err := range Expansions[SearchState](context.Background(), States{},
+		graphState{g, "a"}, nil) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if visit.Goal != (visit.State.String() == "d") || visit.Cost != visit.Depth {
+			t.Errorf("unexpected visit %+v", visit)
+		}
+		visited += visit.State.String()
+	}
+	if visited != "abcd" {
+		t.Errorf("visited %q  (expected %q)", visited, "abcd")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	var want SearchResult
+	expected, err := SearchContext(context.Background(), words(6), "",
+		&Options[string]{Result: &want})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop the search part way through, then resume it.
+	name := t.TempDir() + "/search.ckpt"
+	ctx, cancel := context.WithCancel(context.Background())
+	expanded := 0
+	_, err = SearchContext(ctx, words(6), "", &Options[string]{
+		Checkpoint:         name,
+		CheckpointInterval: time.Nanosecond,
+		Observers: []Observer[string]{Hooks[string]{OnExpand: func(string, int) {
+			if expanded++; expanded == 3 {
+				cancel()
+			}
+		}}},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted search returned error %v  (expected %v)", err, context.Canceled)
+	}
+
+	var result SearchResult
+	path, err := Resume[string, string](context.Background(), words(6), name,
+		&Options[string]{Result: &result})
+	if err != nil || strings.Join(path, ",") != strings.Join(expected, ",") {
+		t.Errorf("resumed search returned %q, error %v  (expected %q)", path, err, expected)
+	}
+	if result.Expanded != want.Expanded || result.Generated != want.Generated ||
+		result.Cost != want.Cost {
+		t.Errorf("resumed search result %+v  (expected %+v)", result, want)
+	}
+
+	if _, err := Resume[string, string](context.Background(), words(6),
+		name+".missing", nil); err == nil {
+		t.Errorf("resumed from a missing checkpoint without error")
+	}
+
+	// Only an ExactSet can be checkpointed, which is checked up front.
+	for _, visited := range []VisitedSet{LRUSet{Capacity: 100}, BloomSet{}} {
+		result = SearchResult{}
+		_, err := SearchContext(context.Background(), words(6), "", &Options[string]{
+			Checkpoint: name, Visited: visited, Result: &result})
+		if err == nil || result.Expanded != 0 {
+			t.Errorf("%#v: checkpointed search expanded %d states, error %v  (expected an error at once)",
+				visited, result.Expanded, err)
+		}
+	}
+}
+
+func TestVisitedSets(t *testing.T) {
+	g := &graph{
+		edges: map[string][]string{
+			"s": {"a", "b", "c"},
+			"a": {"b"},
+			"b": {"g"},
+			"c": {"e"},
+			"e": {"g"},
+		},
+		h:    map[string]int{"a": 3},
+		goal: "g",
+	}
+	// s -> a -> b -> g is cheapest, but the heuristic is not consistent,
+	// so b is first expanded via s -> b.
+	cost := map[string]int{"sa": 1, "sb": 3, "ab": 1, "bg": 3, "sc": 3, "ce": 3, "eg": 3}
+	tests := []struct {
+		visited  VisitedSet
+		expected string
+	}{
+		{nil, "sabg"},
+		{ExactSet{}, "sabg"},
+		{LRUSet{Capacity: 1}, "sabg"},
+		{LRUSet{Capacity: 100}, "sabg"},
+		{BloomSet{Capacity: 100}, "sbg"}, // b is not expanded again
+		{BloomSet{}, "sbg"},
+	}
+	for _, test := range tests {
+		var result SearchResult
+		path, err := SearchContext[SearchState](context.Background(), States{},
+			weightedState{graphState{g, "s"}, cost},
+			&Options[SearchState]{Visited: test.visited, Result: &result})
+		if names := pathNames(path); err != nil || names != test.expected {
+			t.Errorf("%#v: path %q, error %v  (expected %q)", test.visited,
+				names, err, test.expected)
+		}
+		// A BloomSet may drop states, so it guarantees nothing.
+		expected := 1.0
+		if _, bloom := test.visited.(BloomSet); bloom {
+			expected = math.Inf(1)
+		}
+		if result.Bound != expected {
+			t.Errorf("%#v: bound %v  (expected %v)", test.visited, result.Bound, expected)
+		}
+	}
+}
+
+func TestLRUSetUnsolvable(t *testing.T) {
+	// With no goal to reach, forgotten states are queued again and again
+	// round the cycle, so only the limit stops the search.
+	g := &graph{
+		edges: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+		goal:  "z",
+	}
+	path, err := SearchContext[SearchState](context.Background(), States{}, graphState{g, "a"},
+		&Options[SearchState]{Visited: LRUSet{Capacity: 2}, MaxExpanded: 1000})
+	if err != ErrMaxExpanded || path != nil {
+		t.Errorf("path %q, error %v  (expected %v)", pathNames(path), err, ErrMaxExpanded)
+	}
+}
+
+func TestBloomSet(t *testing.T) {
+	const n = 10000
+	set := newBloomSet[int](n, 0.01)
+	for i := 0; i < n; i++ {
+		set.expand(i, 0)
+	}
+	for i := 0; i < n; i++ {
+		if set.current(i, 0) {
+			t.Fatalf("%d was expanded, but is reported as not expanded", i)
+		}
+	}
+	falsePositives := 0
+	for i := n; i < 2*n; i++ {
+		if !set.current(i, 0) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.02 {
+		t.Errorf("false positive rate %.4f  (expected about 0.01)", rate)
+	}
+}
+
+func TestLRUSet(t *testing.T) {
+	set := newLRUSet[string](2)
+	set.reach("a", 1)
+	set.reach("b", 1)
+	set.reach("a", 2) // touches a, so b is forgotten next
+	set.reach("c", 1)
+	if set.order.Len() != 2 {
+		t.Errorf("%d entries  (expected 2)", set.order.Len())
+	}
+	if set.reach("a", 1) || !set.reach("b", 5) {
+		t.Errorf("a should be remembered and b forgotten")
+	}
+}
+
+// interval is the Space of the integers from -10 to 10, each a step from
+// its neighbors, with the distance between them as the heuristic.  No
+// state is Done.
+type interval struct{}
+
+func (interval) Key(n int) int             { return n }
+func (interval) Heuristic(n int) int       { return 0 }
+func (interval) Done(n int) bool           { return false }
+func (interval) Distance(from, to int) int { return max(from-to, to-from) }
+func (interval) Successors(n int) []Successor[int] {
+	result := []Successor[int]{}
+	for _, next := range []int{n - 1, n + 1} {
+		if next >= -10 && next <= 10 {
+			result = append(result, Successor[int]{next, 1})
+		}
+	}
+	return result
+}
+
+func TestGoalPredicate(t *testing.T) {
+	searches := map[string]func(context.Context, Space[int, int], int, *Options[int]) ([]int, error){
+		"SearchContext": SearchContext[int, int],
+		"BFS":           BFS[int, int],
+		"Dijkstra":      Dijkstra[int, int],
+		"DFS":           DFS[int, int],
+		"IDAStar":       IDAStar[int, int],
+		"ParallelSearch": func(ctx context.Context, space Space[int, int], start int,
+			opts *Options[int]) ([]int, error) {
+			return ParallelSearch(ctx, space, start, 4, opts)
+		},
+	}
+	for name, search := range searches {
+		path, err := search(context.Background(), interval{}, 2,
+			&Options[int]{Goal: func(n int) bool { return n*n == 49 }})
+		if err != nil || path[0] != 2 || path[len(path)-1]*path[len(path)-1] != 49 {
+			t.Errorf("%s: path %v, error %v  (expected a path from 2 to a goal)", name, path, err)
+		}
+		if name != "DFS" && len(path) != 6 {
+			t.Errorf("%s: path %v  (expected 5 steps to 7)", name, path)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	cases := [...]struct {
+		start  int
+		goals  []int
+		goal   int
+		nsteps int
+	}{
+		{0, []int{7, -3, 10}, 1, 3},
+		{9, []int{7, -3, 10}, 2, 1},
+		{5, []int{5}, 0, 0},
+	}
+	for ncase, item := range cases {
+		var result SearchResult
+		path, goal, err := Nearest(context.Background(), interval{}, item.start,
+			item.goals, &Options[int]{Result: &result})
+		if err != nil || goal != item.goal || len(path)-1 != item.nsteps {
+			t.Errorf("[Case %d] goal %d in %d steps, error %v  (expected %d in %d)",
+				ncase, goal, len(path)-1, err, item.goal, item.nsteps)
+		}
+		if result.Expanded != item.nsteps {
+			// The heuristic leads straight to the nearest goal.
+			t.Errorf("[Case %d] %d states expanded  (expected %d)", ncase,
+				result.Expanded, item.nsteps)
+		}
+	}
+
+	if _, goal, err := Nearest(context.Background(), interval{}, 0, []int{20}, nil); err != ErrNoPath || goal != -1 {
+		t.Errorf("unreachable goal: goal %d, error %v  (expected -1, %v)", goal, err, ErrNoPath)
+	}
+}
+
+// text is a state that is its own String.
+type text string
+
+func (t text) String() string { return string(t) }
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestRenderers(t *testing.T) {
+	path := []text{"a\nb\nc", "a\nB\nc", "a\nB"}
+	tests := []struct {
+		name     string
+		render   Renderer[text]
+		expected string
+	}{
+		{"text", RenderText[text],
+			"\nStep 0\na\nb\nc\n\nStep 1\na\nB\nc\n\nStep 2\na\nB\n** DONE **\n\n"},
+		{"compact", RenderCompact[text],
+			"0  a | b | c\n1  a | B | c\n2  a | B\n"},
+		{"json", RenderJSON[text], `[
+  {
+    "step": 0,
+    "state": "a\nb\nc"
+  },
+  {
+    "step": 1,
+    "state": "a\nB\nc"
+  },
+  {
+    "step": 2,
+    "state": "a\nB"
+  }
+]
+`},
+		{"diff", RenderDiff[text],
+			"\nStep 0\na\nb\nc\n\nStep 1\n- b\n+ B\n\nStep 2\n- c\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := test.render(&buf, path); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: rendered\n%q\n(expected)\n%q", test.name, buf.String(), test.expected)
+		}
+		if err := test.render(failWriter{}, path); err == nil {
+			t.Errorf("%s: write error not returned", test.name)
+		}
+	}
+}
+
+// lattice is a Space of the paths of R and D moves from the corner of an
+// n by n square, keyed by the position they reach.  Every path to the far
+// corner is a shortest one, and every state has the same priority.
+type lattice int
+
+func (n lattice) Key(s string) string {
+	return fmt.Sprint(strings.Count(s, "R"), strings.Count(s, "D"))
+}
+func (n lattice) Heuristic(s string) int { return 2*int(n) - len(s) }
+func (n lattice) Done(s string) bool     { return len(s) == 2*int(n) }
+
+func (n lattice) Successors(s string) []Successor[string] {
+	result := []Successor[string]{}
+	for _, move := range []string{"R", "D"} {
+		if strings.Count(s, move) < int(n) {
+			result = append(result, Successor[string]{s + move, 1})
+		}
+	}
+	return result
+}
+
+func TestTieBreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options[string]
+		path     string
+		expanded int
+	}{
+		{"FIFO", Options[string]{}, "RRDD", 8},
+		{"LIFO", Options[string]{Tie: LIFO}, "DDRR", 4},
+		{"LowestH", Options[string]{Tie: LowestH}, "RRDD", 4},
+		{"Compare", Options[string]{Compare: strings.Compare}, "DDRR", 4},
+		{"Compare+LIFO", Options[string]{Tie: LIFO,
+			Compare: func(a, b string) int { return strings.Count(a, "D") - strings.Count(b, "D") }},
+			"RRDD", 8},
+	}
+	for _, test := range tests {
+		// The same options always give the same path.
+		for run := 0; run < 3; run++ {
+			var result SearchResult
+			opts := test.opts
+			opts.Result = &result
+			path, err := SearchContext[string, string](context.Background(), lattice(2), "", &opts)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", test.name, err)
+			}
+			if path[len(path)-1] != test.path || result.Expanded != test.expanded {
+				t.Errorf("%s: found %q after expanding %d states  (expected %q after %d)",
+					test.name, path[len(path)-1], result.Expanded, test.path, test.expanded)
+			}
+		}
+	}
+}
+
+func TestKShortest(t *testing.T) {
+	var result SearchResult
+	solutions, err := KShortest[string, string](context.Background(), words(2), "", 3,
+		&Options[string]{Result: &result})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []Solution[string]{
+		{[]string{"", "a", "aa"}, 2, 1},
+		{[]string{"", "b", "ba"}, 3, 1},
+		{[]string{"", "a", "ab"}, 3, 1},
+	}
+	if len(solutions) != len(expected) {
+		t.Fatalf("found %v  (expected %v)", solutions, expected)
+	}
+	for i, solution := range solutions {
+		if fmt.Sprint(solution) != fmt.Sprint(expected[i]) {
+			t.Errorf("solution %d is %v  (expected %v)", i, solution, expected[i])
+		}
+	}
+	if result.Cost != 3 || result.Expanded == 0 {
+		t.Errorf("result %+v  (expected cost 3, totals of all searches)", result)
+	}
+
+	// All 6 paths across a 2 by 2 lattice are shortest, and distinct.
+	solutions, err = KShortest[string, string](context.Background(), lattice(2), "", 10, nil)
+	if err != nil || len(solutions) != 6 {
+		t.Fatalf("found %d paths, error %v  (expected 6)", len(solutions), err)
+	}
+	seen := map[string]bool{}
+	for _, solution := range solutions {
+		last := solution.Path[len(solution.Path)-1]
+		if solution.Cost != 4 || seen[last] {
+			t.Errorf("path %q costs %d  (expected 4, and no duplicates)", last, solution.Cost)
+		}
+		seen[last] = true
+	}
+	if n := Diverge[string, string](lattice(2), solutions[0].Path, solutions[1].Path); n != 1 {
+		t.Errorf("%q and %q diverge after %d states  (expected 1)",
+			solutions[0].Path, solutions[1].Path, n)
+	}
+
+	// The paths found before a search fails are returned with its error.
+	solutions, err = KShortest[string, string](context.Background(), lattice(2), "", 10,
+		&Options[string]{MaxExpanded: 10})
+	if err != ErrMaxExpanded || len(solutions) != 1 {
+		t.Errorf("found %d paths, error %v  (expected 1, %v)", len(solutions), err, ErrMaxExpanded)
+	}
+	if _, err = KShortest[string, string](context.Background(), words(2), "", 3,
+		&Options[string]{MaxDepth: 1}); err != ErrMaxDepth {
+		t.Errorf("error %v  (expected %v)", err, ErrMaxDepth)
+	}
+	noGoal := line(4)
+	noGoal.goal = "z"
+	if solutions, err := KShortest[SearchState](context.Background(), States{},
+		SearchState(graphState{noGoal, "a"}), 3, nil); err != ErrNoPath || solutions != nil {
+		t.Errorf("found %d paths, error %v  (expected none, %v)", len(solutions), err, ErrNoPath)
+	}
+
+	// The paths carry the bound of the searches that found them.
+	solutions, err = KShortest[string, string](context.Background(), words(2), "", 3,
+		&Options[string]{Visited: BloomSet{}, Result: &result})
+	if err != nil || len(solutions) != 3 {
+		t.Fatalf("found %d paths, error %v  (expected 3)", len(solutions), err)
+	}
+	for _, solution := range solutions {
+		if !math.IsInf(solution.Bound, 1) {
+			t.Errorf("path %q has bound %v  (expected +Inf)", solution.Path, solution.Bound)
+		}
+	}
+	if !math.IsInf(result.Bound, 1) {
+		t.Errorf("result bound %v  (expected +Inf)", result.Bound)
+	}
+}
+
+func TestPatternDB(t *testing.T) {
+	// The abstraction of a word is its length, which is one step closer to
+	// the goal length with each letter added.
+	length := func(s string) int { return len(s) }
+	shorter := func(n int) []Successor[int] {
+		if n == 0 {
+			return nil
+		}
+		return []Successor[int]{{n - 1, 1}}
+	}
+	var result SearchResult
+	pdb, err := NewPatternDB(context.Background(), length, []int{4}, shorter,
+		&Options[int]{Result: &result})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if pdb.Len() != 5 || result.Expanded != 5 {
+		t.Errorf("%d abstract states, %d expanded  (expected 5, 5)", pdb.Len(), result.Expanded)
+	}
+	if h := pdb.Heuristic("a"); h != 3 {
+		t.Errorf("heuristic of %q is %d  (expected 3)", "a", h)
+	}
+	if h, ok := pdb.Lookup("aaaaa"); ok || h != 0 {
+		t.Errorf("heuristic of %q is %d, %v  (expected none)", "aaaaa", h, ok)
+	}
+	path, err := SearchContext(context.Background(), WithHeuristic[string, string](words(4), pdb.Heuristic),
+		"", &Options[string]{Result: &result})
+	if err != nil || path[len(path)-1] != "aaaa" || result.Expanded != 4 {
+		t.Errorf("found %q after expanding %d states, error %v  (expected %q after 4)",
+			path, result.Expanded, err, "aaaa")
+	}
+
+	// The database can be saved and loaded again.
+	var buf bytes.Buffer
+	if err := pdb.Save(&buf); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	loaded, err := LoadPatternDB(&buf, length)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if loaded.Len() != 5 || loaded.Heuristic("") != 4 {
+		t.Errorf("loaded %d abstract states, heuristic %d  (expected 5, 4)",
+			loaded.Len(), loaded.Heuristic(""))
+	}
+	if _, err := LoadPatternDB(strings.NewReader("junk"), length); err == nil {
+		t.Errorf("junk loaded without error")
+	}
+
+	if _, err := NewPatternDB(context.Background(), length, []int{4}, shorter,
+		&Options[int]{MaxExpanded: 2}); err != ErrMaxExpanded {
+		t.Errorf("error %v  (expected %v)", err, ErrMaxExpanded)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewPatternDB(ctx, length, []int{4}, shorter, nil); err != context.Canceled {
+		t.Errorf("error %v  (expected %v)", err, context.Canceled)
+	}
+}
+
+func TestExternalBFS(t *testing.T) {
+	dir := t.TempDir()
+	var result SearchResult
+	path, err := ExternalBFS[string, string](context.Background(), lattice(3), "", dir, 2,
+		&Options[string]{Result: &result})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(path) != 7 || path[0] != "" || !lattice(3).Done(path[6]) {
+		t.Errorf("path %q  (expected 6 steps across the lattice)", path)
+	}
+	for i := 1; i < len(path); i++ {
+		if !strings.HasPrefix(path[i], path[i-1]) {
+			t.Errorf("path %q does not follow the moves", path)
+			break
+		}
+	}
+	if result.Cost != 6 || result.Expanded != 15 || result.Duplicates == 0 {
+		t.Errorf("result %+v  (expected cost 6, 15 states expanded, some duplicates)", result)
+	}
+	if files, err := os.ReadDir(dir); err != nil || len(files) != 0 {
+		t.Errorf("%d files left behind, error %v", len(files), err)
+	}
+
+	ints, err := ExternalBFS[int, int](context.Background(), interval{}, 0, dir, 3,
+		&Options[int]{Goal: func(n int) bool { return n == -7 }})
+	if err != nil || len(ints) != 8 || ints[7] != -7 {
+		t.Errorf("path %v, error %v  (expected 7 steps to -7)", ints, err)
+	}
+
+	cases := [...]struct {
+		name string
+		opts *Options[int]
+		err  error
+	}{
+		{"exhausted", nil, ErrNoPath},
+		{"expanded", &Options[int]{MaxExpanded: 5}, ErrMaxExpanded},
+		{"depth", &Options[int]{MaxDepth: 3, Goal: func(n int) bool { return n == 8 }}, ErrMaxDepth},
+	}
+	for _, item := range cases {
+		path, err := ExternalBFS[int, int](context.Background(), interval{}, 0, dir, 3, item.opts)
+		if err != item.err || path != nil {
+			t.Errorf("[%s] path %v, error %v  (expected %v)", item.name, path, err, item.err)
+		}
+	}
 }
@@ -0,0 +1,139 @@
+// Package astartest checks that state types meet the expectations of the
+// astar package, so that each new puzzle gets some meaningful tests for
+// free:
+//
+//	func TestState(t *testing.T) {
+//		astartest.Check(t, &initState, 1000)
+//	}
+package astartest
+
+import (
+	"fmt"
+	"github.com/tomp/aoc-2016-go/astar"
+	"reflect"
+	"testing"
+)
+
+// Check explores up to budget of the states reachable from start,
+// breadth-first, and reports with t.Errorf each way in which they fail to
+// behave as astar.States expects:
+//
+//   - Hash returns the same value every time it is called on a state, and
+//     for states that are equal (as compared by reflect.DeepEqual);
+//   - the neighbors of a state all have distinct hashes;
+//   - states that are Done have a heuristic of 0;
+//   - the heuristic is never negative;
+//   - step costs are never negative, for a WeightedSearchState;
+//   - String never panics.
+//
+// Only the first few failures of each kind are reported.
+func Check(t testing.TB, start astar.SearchState, budget int) {
+	t.Helper()
+	CheckSpace[astar.SearchState](t, astar.States{}, start, budget)
+}
+
+// CheckSpace is like Check, for any astar.Space.  Key takes the place of
+// Hash, and String is only checked for states that implement fmt.Stringer.
+func CheckSpace[S any, K comparable](t testing.TB, space astar.Space[S, K],
+	start S, budget int) {
+
+	t.Helper()
+	c := checker{t: t, failures: map[string]int{}}
+	seen := map[K]bool{space.Key(start): true}
+	queue := []S{start}
+	for n := 0; n < budget && len(queue) > 0; n, queue = n+1, queue[1:] {
+		state := queue[0]
+		name := c.name(state)
+		key := space.Key(state)
+
+		if again := space.Key(state); again != key {
+			c.fail("unstable key", "%s has key %v, then %v", name, key, again)
+		}
+		h := space.Heuristic(state)
+		if h < 0 {
+			c.fail("negative heuristic", "%s has heuristic %d", name, h)
+		}
+		if space.Done(state) && h != 0 {
+			c.fail("goal heuristic", "goal %s has heuristic %d, not 0", name, h)
+		}
+
+		// Generate the successors twice: equal states must have equal
+		// keys, and the successors of a state must have distinct keys.
+		succs, again := space.Successors(state), space.Successors(state)
+		keys := map[K]int{}
+		for i, next := range succs {
+			nextKey := space.Key(next.State)
+			if next.Cost < 0 {
+				c.fail("negative cost", "step from %s to %s costs %d",
+					name, c.name(next.State), next.Cost)
+			}
+			if j, ok := keys[nextKey]; ok {
+				c.fail("duplicate neighbors", "neighbors %d and %d of %s have the same key %v",
+					j, i, name, nextKey)
+			}
+			keys[nextKey] = i
+			if i < len(again) && reflect.DeepEqual(next.State, again[i].State) {
+				if againKey := space.Key(again[i].State); againKey != nextKey {
+					c.fail("unequal keys", "equal states %s have keys %v and %v",
+						c.name(next.State), nextKey, againKey)
+				}
+			}
+			if !seen[nextKey] {
+				seen[nextKey] = true
+				queue = append(queue, next.State)
+			}
+		}
+	}
+	c.report()
+}
+
+// maxReports is the number of failures of each kind reported in full.
+const maxReports = 3
+
+// checker collects the failures found by CheckSpace.
+type checker struct {
+	t        testing.TB
+	failures map[string]int // number of failures of each kind
+	kinds    []string       // kinds of failure, in the order found
+}
+
+// fail records a failure of the given kind, reporting it unless enough of
+// that kind have been reported already.
+func (c *checker) fail(kind string, format string, args ...interface{}) {
+	c.t.Helper()
+	if c.failures[kind] == 0 {
+		c.kinds = append(c.kinds, kind)
+	}
+	c.failures[kind]++
+	if c.failures[kind] <= maxReports {
+		c.t.Errorf(kind+": "+format, args...)
+	}
+}
+
+// report reports the number of failures of each kind beyond those already
+// reported.
+func (c *checker) report() {
+	c.t.Helper()
+	for _, kind := range c.kinds {
+		if n := c.failures[kind]; n > maxReports {
+			c.t.Errorf("%s: %d more failures", kind, n-maxReports)
+		}
+	}
+}
+
+// name returns the String of state, for use in reports, and reports a
+// failure if String panics.
+func (c *checker) name(state any) (name string) {
+	c.t.Helper()
+	s, ok := state.(fmt.Stringer)
+	if !ok {
+		return fmt.Sprintf("%#v", state)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.fail("String panics", "String panics on %#v: %v", state, r)
+			name = fmt.Sprintf("%#v", state)
+		}
+	}()
+	return fmt.Sprintf("%q", s.String())
+}
@@ -0,0 +1,141 @@
+package astartest
+
+import (
+	"fmt"
+	"github.com/tomp/aoc-2016-go/astar"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB that records the errors reported to it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// num is a state whose String panics for 7.
+type num int
+
+func (n num) String() string {
+	if n == 7 {
+		panic("seven")
+	}
+	return strconv.Itoa(int(n))
+}
+
+// line is the Space of the numbers from 0 to its last, each leading to the
+// next one and the one after.  flaw makes it break one of the rules.
+type line struct {
+	last  num
+	flaw  string
+	calls *int
+}
+
+func (l line) Key(n num) int {
+	if l.flaw == "unstable" {
+		*l.calls++
+		return int(n)*100 + *l.calls
+	}
+	return int(n)
+}
+
+func (l line) Heuristic(n num) int {
+	if l.flaw == "negative" {
+		return -1
+	}
+	if l.flaw == "goal" {
+		return 1
+	}
+	return int(l.last - n)
+}
+
+func (l line) Done(n num) bool { return n == l.last }
+
+func (l line) Successors(n num) []astar.Successor[num] {
+	result := []astar.Successor[num]{}
+	for _, next := range []num{n + 1, n + 2} {
+		if next <= l.last {
+			result = append(result, astar.Successor[num]{State: next, Cost: 1})
+		}
+	}
+	switch {
+	case l.flaw == "duplicate" && len(result) > 0:
+		result = append(result, result[0])
+	case l.flaw == "cost" && len(result) > 0:
+		result[0].Cost = -1
+	}
+	return result
+}
+
+func TestCheckSpace(t *testing.T) {
+	cases := [...]struct {
+		space line
+		kinds []string // the kinds of failure expected
+	}{
+		{line{last: 5}, nil},
+		{line{last: 5, flaw: "unstable", calls: new(int)}, []string{"unstable key", "unequal keys"}},
+		{line{last: 1, flaw: "negative"}, []string{"negative heuristic", "goal heuristic"}},
+		{line{last: 1, flaw: "goal"}, []string{"goal heuristic"}},
+		{line{last: 2, flaw: "duplicate"}, []string{"duplicate neighbors"}},
+		{line{last: 2, flaw: "cost"}, []string{"negative cost"}},
+		{line{last: 8}, []string{"String panics"}},
+	}
+
+	for ncase, item := range cases {
+		r := &recorder{TB: t}
+		CheckSpace[num, int](r, item.space, 0, 100)
+		found := map[string]bool{}
+		for _, err := range r.errors {
+			kind, _, _ := strings.Cut(err, ":")
+			found[kind] = true
+		}
+		if len(found) != len(item.kinds) {
+			t.Errorf("[Case %d] errors %q  (expected kinds %q)", ncase, r.errors, item.kinds)
+			continue
+		}
+		for _, kind := range item.kinds {
+			if !found[kind] {
+				t.Errorf("[Case %d] errors %q  (expected kinds %q)", ncase, r.errors, item.kinds)
+				break
+			}
+		}
+	}
+
+	// Only the first few failures of each kind are reported in full.
+	r := &recorder{TB: t}
+	CheckSpace[num, int](r, line{last: 6, flaw: "negative"}, 0, 100)
+	if len(r.errors) != maxReports+2 || !strings.Contains(r.errors[maxReports+1], "more failures") {
+		t.Errorf("errors %q  (expected %d, then a count)", r.errors, maxReports+1)
+	}
+}
+
+// budget is a SearchState with an infinite number of states.
+type budget struct {
+	n     int
+	count *int // number of states whose neighbors have been generated
+}
+
+func (b budget) String() string { return strconv.Itoa(b.n) }
+func (b budget) Hash() string   { return b.String() }
+func (b budget) Heuristic() int { return 1 }
+func (b budget) Done() bool     { return false }
+
+func (b budget) AstarNextStates() []astar.SearchState {
+	*b.count++
+	return []astar.SearchState{budget{b.n + 1, b.count}}
+}
+
+func TestCheckBudget(t *testing.T) {
+	count := 0
+	Check(t, budget{0, &count}, 10)
+	if count != 20 { // each state's neighbors are generated twice
+		t.Errorf("neighbors generated %d times  (expected 20)", count)
+	}
+}
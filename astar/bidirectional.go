@@ -0,0 +1,176 @@
+package astar
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"time"
+)
+
+// frontier is one half of a bidirectional search.
+type frontier[S any, K comparable] struct {
+	tree   searchTree[S]
+	queue  SearchQueue[S]
+	gScore map[K]int // cheapest known cost from this side's roots
+	nodes  map[K]int // tree node of the cheapest known path to each state
+	closed map[K]bool
+	next   func(S) []Successor[S]
+}
+
+func newFrontier[S any, K comparable](next func(S) []Successor[S]) *frontier[S, K] {
+	return &frontier[S, K]{
+		gScore: map[K]int{},
+		nodes:  map[K]int{},
+		closed: map[K]bool{},
+		next:   next,
+	}
+}
+
+// add queues state with the given cost, if it is cheaper than any path to
+// it found so far.  It reports whether the state was queued.
+func (f *frontier[S, K]) add(key K, state S, cost, parent int) bool {
+	if best, ok := f.gScore[key]; ok && best <= cost {
+		return false
+	}
+	n := f.tree.add(state, parent, cost)
+	f.gScore[key] = cost
+	f.nodes[key] = n
+	delete(f.closed, key)
+	heap.Push(&f.queue, &SearchItem[S]{priority: float64(cost), cost: cost, node: n})
+	return true
+}
+
+// top returns the cost of the cheapest state in the queue.
+func (f *frontier[S, K]) top() int {
+	if f.queue.Len() == 0 {
+		return math.MaxInt
+	}
+	return f.queue.top().cost
+}
+
+// Bidirectional returns the cheapest path from start to one of the goals,
+// searching forward from start and backward from the goals at the same
+// time, until the two searches meet.  In a space where each state has b
+// successors and the path has d steps, this expands on the order of
+// b^(d/2) states instead of b^d.
+//
+// The backward search uses pred to find the states from which a state can
+// be reached, and the cost of each of those steps.  If pred is nil, steps
+// are assumed to be reversible at the same cost, and space.Successors is
+// used in both directions.
+//
+// The heuristic is not used, and the goals are given explicitly, so Done
+// is not called.  opts.MaxDepth is ignored.  The statistics in opts.Result
+// are totals over both directions, and observers see the events of both
+// directions; for the backward search, the "parent" of a state is the
+// state it leads to.
+func Bidirectional[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, goals []S, pred func(S) []Successor[S], opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[S](opts.Observers)
+	if pred == nil {
+		pred = space.Successors
+	}
+
+	fwd := newFrontier[S, K](space.Successors)
+	bwd := newFrontier[S, K](pred)
+	fwd.add(space.Key(start), start, 0, -1)
+	for _, goal := range goals {
+		bwd.add(space.Key(goal), goal, 0, -1)
+	}
+
+	// best is the cost of the cheapest path found so far, which passes
+	// through meetF in the forward tree and meetB in the backward tree.
+	best := math.MaxInt
+	meetF, meetB := -1, -1
+	if n, ok := bwd.nodes[space.Key(start)]; ok {
+		best, meetF, meetB = 0, 0, n
+	}
+
+	for fwd.queue.Len() > 0 && bwd.queue.Len() > 0 {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		// No path through the unexpanded states can beat the best one
+		// found so far.
+		if best < math.MaxInt && fwd.top()+bwd.top() >= best {
+			break
+		}
+
+		// Expand the side with the smaller frontier.
+		this, other := fwd, bwd
+		if bwd.queue.Len() < fwd.queue.Len() {
+			this, other = bwd, fwd
+		}
+
+		item := heap.Pop(&this.queue).(*SearchItem[S])
+		state := this.tree[item.node].state
+		key := space.Key(state)
+		if this.closed[key] || item.cost > this.gScore[key] {
+			continue
+		}
+		if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+			err = ErrMaxExpanded
+			return
+		}
+
+		result.Expanded += 1
+		obs.expand(state, item.cost)
+		this.closed[key] = true
+		for _, next := range this.next(state) {
+			result.Generated += 1
+			cost := item.cost + next.Cost
+			nextKey := space.Key(next.State)
+			if !this.add(nextKey, next.State, cost, item.node) {
+				result.Duplicates += 1
+				obs.prune(next.State, state, cost)
+				continue
+			}
+			obs.enqueue(next.State, state, cost)
+			if g, ok := other.gScore[nextKey]; ok && cost+g < best {
+				best = cost + g
+				if this == fwd {
+					meetF, meetB = this.nodes[nextKey], other.nodes[nextKey]
+				} else {
+					meetF, meetB = other.nodes[nextKey], this.nodes[nextKey]
+				}
+			}
+		}
+		result.frontier(fwd.queue.Len() + bwd.queue.Len())
+	}
+
+	if meetF < 0 {
+		err = ErrNoPath
+		return
+	}
+
+	// Stitch the two halves together at the meeting state.  The second
+	// half is rebuilt from the successors of each state, rather than
+	// copied from the backward tree, since states with equal keys need
+	// not be identical.
+	path = fwd.tree.path(meetF)
+	back := bwd.tree.path(meetB)
+	for i := len(back) - 2; i >= 0; i-- {
+		path = append(path, successorWithKey(space, path[len(path)-1], back[i]))
+	}
+	result.found(best, len(path)-1, 1)
+	obs.goal(path, best)
+	return
+}
+
+// successorWithKey returns the successor of state with the same key as
+// target, or target itself if there is none.
+func successorWithKey[S any, K comparable](space Space[S, K], state, target S) S {
+	key := space.Key(target)
+	for _, next := range space.Successors(state) {
+		if space.Key(next.State) == key {
+			return next.State
+		}
+	}
+	return target
+}
@@ -0,0 +1,143 @@
+package astar
+
+import (
+	"container/heap"
+	"context"
+	"math"
+)
+
+// Overestimate is a state whose heuristic is greater than the cost of
+// reaching a goal from it.
+type Overestimate[S any] struct {
+	State     S
+	Heuristic int
+	CostToGo  int // cost of the cheapest path to a goal that was found
+}
+
+// Inconsistency is a step from one state to another across which the
+// heuristic drops by more than the cost of the step, that is h(From) >
+// Cost + h(To).
+type Inconsistency[S any] struct {
+	From, To                   S
+	Cost                       int
+	HeuristicFrom, HeuristicTo int
+}
+
+// HeuristicReport is the result of CheckHeuristic.
+type HeuristicReport[S any] struct {
+	States   int  // number of states examined
+	Complete bool // true if every successor of those states was examined
+
+	// Inadmissible lists the states whose heuristic is greater than the
+	// cost of a path to a goal.  If the heuristic is admissible, it is
+	// empty.
+	Inadmissible []Overestimate[S]
+
+	// Inconsistent lists the steps across which the heuristic is not
+	// consistent.  If the heuristic is consistent, it is empty.
+	Inconsistent []Inconsistency[S]
+}
+
+// Admissible reports whether no inadmissible states were found.
+func (r *HeuristicReport[S]) Admissible() bool { return len(r.Inadmissible) == 0 }
+
+// Consistent reports whether no inconsistent steps were found.
+func (r *HeuristicReport[S]) Consistent() bool { return len(r.Inconsistent) == 0 }
+
+// CheckHeuristic checks the space's heuristic over the region of at most
+// limit states around start (or all the states reachable from start, if
+// limit is not positive).  It explores the region breadth-first, then runs
+// Dijkstra's algorithm backward from the goal states in it to find the
+// cost of reaching a goal from every state, and compares those costs with
+// the heuristic.  Every step found in the region is checked for
+// consistency as well.
+//
+// If the region is not Complete, paths that leave it are not considered,
+// so a cost found may be higher than the true cost to go, and some
+// inadmissible states may go unreported.  But every state that is
+// reported really is inadmissible.
+func CheckHeuristic[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, limit int) (report *HeuristicReport[S], err error) {
+
+	type edge struct {
+		from, to int // indices in states
+		cost     int
+	}
+
+	states := []S{start}
+	index := map[K]int{space.Key(start): 0}
+	edges := []edge{}
+	report = &HeuristicReport[S]{Complete: true}
+
+	// Explore the region, numbering the states in the order found.
+	for i := 0; i < len(states); i++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, next := range space.Successors(states[i]) {
+			key := space.Key(next.State)
+			j, ok := index[key]
+			if !ok {
+				if limit > 0 && len(states) >= limit {
+					report.Complete = false
+					continue
+				}
+				j = len(states)
+				index[key] = j
+				states = append(states, next.State)
+			}
+			edges = append(edges, edge{i, j, next.Cost})
+		}
+	}
+	report.States = len(states)
+
+	h := make([]int, len(states))
+	for i, state := range states {
+		h[i] = space.Heuristic(state)
+	}
+
+	// Run Dijkstra backward from the goals, over the reversed edges.
+	into := make([][]edge, len(states))
+	for _, e := range edges {
+		into[e.to] = append(into[e.to], e)
+	}
+	costToGo := make([]int, len(states))
+	queue := SearchQueue[S]{}
+	for i, state := range states {
+		costToGo[i] = math.MaxInt
+		if space.Done(state) {
+			costToGo[i] = 0
+			heap.Push(&queue, &SearchItem[S]{node: i})
+		}
+	}
+	for queue.Len() > 0 {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		item := heap.Pop(&queue).(*SearchItem[S])
+		if item.cost > costToGo[item.node] {
+			continue
+		}
+		for _, e := range into[item.node] {
+			if cost := item.cost + e.cost; cost < costToGo[e.from] {
+				costToGo[e.from] = cost
+				heap.Push(&queue, &SearchItem[S]{priority: float64(cost), cost: cost,
+					node: e.from})
+			}
+		}
+	}
+
+	for i, state := range states {
+		if costToGo[i] < math.MaxInt && h[i] > costToGo[i] {
+			report.Inadmissible = append(report.Inadmissible,
+				Overestimate[S]{state, h[i], costToGo[i]})
+		}
+	}
+	for _, e := range edges {
+		if h[e.from] > e.cost+h[e.to] {
+			report.Inconsistent = append(report.Inconsistent, Inconsistency[S]{
+				states[e.from], states[e.to], e.cost, h[e.from], h[e.to]})
+		}
+	}
+	return
+}
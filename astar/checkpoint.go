@@ -0,0 +1,203 @@
+package astar
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCheckpointInterval is the time between checkpoints when
+// Options.CheckpointInterval is zero.
+const DefaultCheckpointInterval = time.Minute
+
+// errNotExact is returned by a search with Options.Checkpoint set that
+// does not use an ExactSet.
+var errNotExact = errors.New("astar: only a search with an ExactSet can be checkpointed")
+
+// checkpointVersion identifies the layout of checkpoint files.
+const checkpointVersion = 2
+
+// checkpointFile is the content of a checkpoint file: everything an engine
+// needs to carry on a search, apart from the space and the options.
+type checkpointFile[S any, K comparable] struct {
+	Version   int
+	Tree      []checkpointNode[S]
+	Queue     []checkpointItem // in heap order
+	GScore    map[K]int
+	Closed    []K
+	Seq       int
+	Cut       bool
+	Dropped   bool
+	Incumbent int
+	Best      int
+	Result    SearchResult // including the time elapsed so far
+}
+
+type checkpointNode[S any] struct {
+	State  S
+	Parent int
+	Depth  int
+	Cost   int
+}
+
+type checkpointItem struct {
+	Priority float64
+	Cost     int
+	Node     int
+	H        int
+	Seq      int
+}
+
+// checkpoint saves the engine's progress to opts.Checkpoint, if one is
+// due, or if now is true.
+func (e *engine[S, K]) checkpoint(now bool) error {
+	if e.opts.Checkpoint == "" {
+		return nil
+	}
+	interval := e.opts.CheckpointInterval
+	if interval == 0 {
+		interval = DefaultCheckpointInterval
+	}
+	if !now && time.Since(e.saved) < interval {
+		return nil
+	}
+	visited, ok := e.visited.(*exactSet[K])
+	if !ok {
+		return errNotExact
+	}
+
+	c := checkpointFile[S, K]{
+		Version:   checkpointVersion,
+		Tree:      make([]checkpointNode[S], len(e.tree)),
+		Queue:     make([]checkpointItem, e.queue.Len()),
+		GScore:    visited.cost,
+		Closed:    make([]K, 0, len(visited.closed)),
+		Seq:       e.seq,
+		Cut:       e.cut,
+		Dropped:   e.dropped,
+		Incumbent: e.incumbent,
+		Best:      e.best,
+		Result:    *e.result,
+	}
+	for i, node := range e.tree {
+		c.Tree[i] = checkpointNode[S]{node.state, node.parent, node.depth, node.cost}
+	}
+	for i, item := range e.queue.items {
+		c.Queue[i] = checkpointItem{item.priority, item.cost, item.node, item.h, item.seq}
+	}
+	for key := range visited.closed {
+		c.Closed = append(c.Closed, key)
+	}
+	c.Result.Elapsed = time.Since(e.started)
+
+	if err := writeCheckpoint(e.opts.Checkpoint, &c); err != nil {
+		return fmt.Errorf("astar: saving checkpoint: %w", err)
+	}
+	e.saved = time.Now()
+	return nil
+}
+
+// writeCheckpoint writes c to the named file.  The checkpoint is written
+// to a temporary file first, which then replaces the old one, so that a
+// crash while writing does not lose the last checkpoint.
+func writeCheckpoint[S any, K comparable](name string, c *checkpointFile[S, K]) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // in case of failure
+
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(c)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Resume carries on a search from the checkpoint in the named file, saved
+// by SearchContext with Options.Checkpoint set, and returns what the
+// original search would have.  space must be the same as the original
+// search's, and opts should be too, apart from the limits, which can be
+// changed.  If opts.Checkpoint is set, the resumed search saves
+// checkpoints of its own, which may go to the same file.  The statistics
+// in opts.Result include those of the search before the checkpoint.
+func Resume[S any, K comparable](ctx context.Context, space Space[S, K],
+	name string, opts *Options[S]) (shortestPath []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		if opts.Anytime != nil {
+			close(opts.Anytime)
+		}
+		return nil, err
+	}
+	var c checkpointFile[S, K]
+	err = gob.NewDecoder(bufio.NewReader(f)).Decode(&c)
+	f.Close()
+	if err == nil && c.Version != checkpointVersion {
+		err = fmt.Errorf("unknown version %d", c.Version)
+	}
+	if err != nil {
+		if opts.Anytime != nil {
+			close(opts.Anytime)
+		}
+		return nil, fmt.Errorf("astar: reading checkpoint %s: %w", name, err)
+	}
+
+	if c.GScore == nil {
+		c.GScore = map[K]int{} // gob leaves empty maps nil
+	}
+	visited := &exactSet[K]{c.GScore, make(map[K]bool, len(c.Closed))}
+	for _, key := range c.Closed {
+		visited.closed[key] = true
+	}
+
+	result := opts.result()
+	*result = c.Result
+	e := &engine[S, K]{
+		ctx:       ctx,
+		space:     withGoal(space, opts),
+		opts:      opts,
+		result:    result,
+		obs:       opts.Observers,
+		weight:    max(opts.Weight, 1),
+		started:   time.Now().Add(-c.Result.Elapsed),
+		saved:     time.Now(),
+		tree:      make(searchTree[S], len(c.Tree)),
+		queue:     SearchQueue[S]{items: make([]*SearchItem[S], len(c.Queue))},
+		visited:   visited,
+		seq:       c.Seq,
+		cut:       c.Cut,
+		dropped:   c.Dropped,
+		incumbent: c.Incumbent,
+		best:      c.Best,
+	}
+	for i, node := range c.Tree {
+		e.tree[i] = searchNode[S]{node.State, node.Parent, node.Depth, node.Cost}
+	}
+	for i, item := range c.Queue {
+		e.queue.items[i] = &SearchItem[S]{item.Priority, item.Cost, item.Node,
+			item.H, item.Seq, i}
+	}
+	e.queue.tie = e.tieBreak()
+	defer result.finish(e.started)
+	return e.run()
+}
@@ -0,0 +1,95 @@
+package astar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DotWriter is an Observer that writes the tree explored by a search to w
+// as a Graphviz DOT graph.  Each state is drawn as a node labelled with
+// its String(), so states with the same string share a node.  Queued
+// successors are joined to their parent by solid edges and pruned ones by
+// dashed edges, expanded states are drawn filled, and the path to the goal
+// is drawn in bold.  Close must be called after the search to finish the
+// graph.
+//
+//	dot := astar.NewDotWriter[*rtg.State](f)
+//	path, err := astar.SearchContext(ctx, rtg.Space{}, &state,
+//		&astar.Options[*rtg.State]{Observers: []astar.Observer[*rtg.State]{dot}})
+//	if err := dot.Close(); err != nil {
+//		...
+//	}
+type DotWriter[S fmt.Stringer] struct {
+	w   io.Writer
+	ids map[string]int // node number of each label
+	err error          // first write error
+}
+
+// NewDotWriter returns a DotWriter that writes to w, having written the
+// start of the graph.
+func NewDotWriter[S fmt.Stringer](w io.Writer) *DotWriter[S] {
+	d := &DotWriter[S]{w: w, ids: map[string]int{}}
+	d.printf("digraph search {\n")
+	d.printf("\tnode [shape=box, fontname=monospace];\n")
+	return d
+}
+
+// printf writes to the output, unless an earlier write has failed.
+func (d *DotWriter[S]) printf(format string, args ...interface{}) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// node returns the name of the node for state, declaring it first if it
+// has not been seen before.
+func (d *DotWriter[S]) node(state S) string {
+	label := state.String()
+	id, ok := d.ids[label]
+	if !ok {
+		id = len(d.ids)
+		d.ids[label] = id
+		d.printf("\tn%d [label=\"%s\"];\n", id, dotEscape(label))
+	}
+	return fmt.Sprintf("n%d", id)
+}
+
+func (d *DotWriter[S]) Expand(state S, cost int) {
+	d.printf("\t%s [style=filled, fillcolor=lightgrey, xlabel=\"g=%d\"];\n",
+		d.node(state), cost)
+}
+
+func (d *DotWriter[S]) Enqueue(state, parent S, cost int) {
+	from, to := d.node(parent), d.node(state)
+	d.printf("\t%s -> %s;\n", from, to)
+}
+
+func (d *DotWriter[S]) Prune(state, parent S, cost int) {
+	from, to := d.node(parent), d.node(state)
+	d.printf("\t%s -> %s [style=dashed];\n", from, to)
+}
+
+func (d *DotWriter[S]) Goal(path []S, cost int) {
+	for i, state := range path {
+		d.printf("\t%s [penwidth=3];\n", d.node(state))
+		if i > 0 {
+			d.printf("\t%s -> %s [penwidth=3];\n", d.node(path[i-1]), d.node(state))
+		}
+	}
+}
+
+// Close writes the end of the graph, and returns the first error that
+// occurred while writing it.
+func (d *DotWriter[S]) Close() error {
+	d.printf("}\n")
+	return d.err
+}
+
+// dotEscape escapes a label for use in a quoted DOT string, with each
+// line left-justified.
+func dotEscape(label string) string {
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	label = strings.ReplaceAll(label, `"`, `\"`)
+	return strings.ReplaceAll(label, "\n", `\l`) + `\l`
+}
@@ -0,0 +1,362 @@
+package astar
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"github.com/tomp/aoc-2016-go/pqueue"
+	"hash/maphash"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ExternalBFS returns the path from start to a goal state with the fewest
+// steps, like BFS, for spaces too large to search in memory.  It keeps
+// each layer of the search, the states at the same depth, in a file, and
+// detects duplicates in a layer only once it is complete (delayed
+// duplicate detection): the successors of a layer are sorted by the hash
+// of their keys in a pqueue.ExternalQueue, which holds at most runSize of
+// them in memory, and merged with a file of the keys of all the states
+// reached so far, also sorted by hash.  The files are kept in a temporary
+// directory in dir (or in the default directory for temporary files, if
+// dir is ""), which is removed when the search returns.
+//
+// States and keys are written with encoding/gob, as for Options.Checkpoint,
+// so the same types need registering.  opts may be nil.  Observers only
+// see the states expanded and the goal.
+func ExternalBFS[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, dir string, runSize int, opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	space = withGoal(space, opts)
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[S](opts.Observers)
+	gob.Register(layerRecord[S, K]{})
+
+	tmp, err := os.MkdirTemp(dir, "astar-bfs-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	b := &externalBFS[S, K]{space: space, dir: tmp, seed: maphash.MakeSeed()}
+
+	startKey := space.Key(start)
+	if err = writeRecords(b.layer(0), []layerRecord[S, K]{{State: start}}); err != nil {
+		return nil, err
+	}
+	if err = writeRecords(b.visited(0), []visitedRecord[K]{{b.hash(startKey), startKey}}); err != nil {
+		return nil, err
+	}
+	result.frontier(1)
+
+	cut := false // true if any path was cut off by opts.MaxDepth
+	for depth := 0; ; depth++ {
+		queue := pqueue.NewExternalQueue(tmp, runSize)
+		var goal *layerRecord[S, K]
+		err = readRecords(b.layer(depth), func(rec *layerRecord[S, K]) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if space.Done(rec.State) {
+				goal = rec
+				return errStop
+			}
+			if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+				cut = true
+				return nil
+			}
+			if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+				return ErrMaxExpanded
+			}
+			result.Expanded += 1
+			obs.expand(rec.State, rec.Cost)
+			key := space.Key(rec.State)
+			for _, next := range space.Successors(rec.State) {
+				result.Generated += 1
+				item := layerRecord[S, K]{next.State, key, rec.Cost + next.Cost}
+				if err := queue.Push(pqueue.NewItem(b.hash(space.Key(next.State)), item)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if goal != nil {
+			queue.Close()
+			if path, err = b.path(goal, depth); err != nil {
+				return nil, err
+			}
+			result.found(goal.Cost, depth, math.Inf(1))
+			obs.goal(path, goal.Cost)
+			return path, nil
+		}
+		if err == nil && queue.Len() == 0 {
+			if err = ErrNoPath; cut {
+				err = ErrMaxDepth
+			}
+		}
+		if err == nil {
+			err = b.merge(queue, depth+1, result)
+		}
+		if cerr := queue.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// externalBFS holds the state of ExternalBFS.
+type externalBFS[S any, K comparable] struct {
+	space Space[S, K]
+	dir   string // directory holding the files
+	seed  maphash.Seed
+}
+
+// layerRecord is a state of a layer of ExternalBFS, as written to the
+// layer's file.
+type layerRecord[S any, K comparable] struct {
+	State  S
+	Parent K   // key of the state's parent, in the layer before
+	Cost   int // cost of the path to the state
+}
+
+// visitedRecord is a key of a state reached by ExternalBFS, as written to
+// the file of the keys reached, in order of Hash.
+type visitedRecord[K comparable] struct {
+	Hash int
+	Key  K
+}
+
+func (b *externalBFS[S, K]) hash(key K) int {
+	return int(maphash.Comparable(b.seed, key))
+}
+
+// layer returns the name of the file of the states at the given depth.
+func (b *externalBFS[S, K]) layer(depth int) string {
+	return filepath.Join(b.dir, "layer"+strconv.Itoa(depth))
+}
+
+// visited returns the name of the file of the keys of the states reached
+// up to the given depth.
+func (b *externalBFS[S, K]) visited(depth int) string {
+	return filepath.Join(b.dir, "visited"+strconv.Itoa(depth))
+}
+
+// merge writes the states in queue that have not been reached before to
+// the layer at the given depth, and adds their keys to those reached.
+func (b *externalBFS[S, K]) merge(queue *pqueue.ExternalQueue, depth int, result *SearchResult) error {
+	old, err := newRecordReader[visitedRecord[K]](b.visited(depth - 1))
+	if err != nil {
+		return err
+	}
+	defer old.close()
+	visited, err := newRecordWriter[visitedRecord[K]](b.visited(depth))
+	if err != nil {
+		return err
+	}
+	defer visited.close()
+	layer, err := newRecordWriter[layerRecord[S, K]](b.layer(depth))
+	if err != nil {
+		return err
+	}
+	defer layer.close()
+
+	// The queue and the old keys are both in order of hash, so the keys
+	// that share a hash with each state in the queue are found by reading
+	// the two side by side.
+	var seen map[K]bool
+	group := 0
+	for queue.Len() > 0 {
+		item, err := queue.Pop()
+		if err != nil {
+			return err
+		}
+		if seen == nil || item.Priority != group {
+			group, seen = item.Priority, map[K]bool{}
+			for old.ok && old.rec.Hash <= group {
+				if old.rec.Hash == group {
+					seen[old.rec.Key] = true
+				}
+				if err := visited.write(&old.rec); err != nil {
+					return err
+				}
+				if err := old.next(); err != nil {
+					return err
+				}
+			}
+		}
+		rec := item.Value.(layerRecord[S, K])
+		key := b.space.Key(rec.State)
+		if seen[key] {
+			result.Duplicates += 1
+			continue
+		}
+		seen[key] = true
+		if err = visited.write(&visitedRecord[K]{group, key}); err == nil {
+			err = layer.write(&rec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for old.ok {
+		if err := visited.write(&old.rec); err != nil {
+			return err
+		}
+		if err := old.next(); err != nil {
+			return err
+		}
+	}
+	err = visited.close()
+	if err == nil {
+		err = layer.close()
+	}
+	if err == nil {
+		result.frontier(layer.n)
+		err = os.Remove(b.visited(depth - 1))
+	}
+	return err
+}
+
+// path returns the path to the goal state, found at the given depth, by
+// looking for the parent of each state in the layer before it.
+func (b *externalBFS[S, K]) path(goal *layerRecord[S, K], depth int) ([]S, error) {
+	path := make([]S, depth+1)
+	path[depth] = goal.State
+	parent := goal.Parent
+	for d := depth - 1; d >= 0; d-- {
+		found := false
+		err := readRecords(b.layer(d), func(rec *layerRecord[S, K]) error {
+			if b.space.Key(rec.State) != parent {
+				return nil
+			}
+			path[d], parent, found = rec.State, rec.Parent, true
+			return errStop
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, errors.New("astar: parent state missing from its layer")
+		}
+	}
+	return path, nil
+}
+
+// errStop stops readRecords early, without an error.
+var errStop = errors.New("stop")
+
+// readRecords calls fn with each record in the named file, until it
+// returns an error, which is returned unless it is errStop.
+func readRecords[T any](name string, fn func(rec *T) error) error {
+	r, err := newRecordReader[T](name)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+	for ; r.ok && err == nil; err = r.next() {
+		rec := r.rec
+		if err = fn(&rec); err != nil {
+			break
+		}
+	}
+	if err == errStop {
+		return nil
+	}
+	return err
+}
+
+// writeRecords writes the records to the named file.
+func writeRecords[T any](name string, recs []T) error {
+	w, err := newRecordWriter[T](name)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+	for i := 0; i < len(recs) && err == nil; i++ {
+		err = w.write(&recs[i])
+	}
+	if err != nil {
+		return err
+	}
+	return w.close()
+}
+
+// recordReader reads gob-encoded records from a file, one at a time.
+type recordReader[T any] struct {
+	file *os.File
+	dec  *gob.Decoder
+	rec  T    // the current record
+	ok   bool // false once the file is exhausted
+}
+
+func newRecordReader[T any](name string) (*recordReader[T], error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &recordReader[T]{file: f, dec: gob.NewDecoder(bufio.NewReader(f))}
+	if err = r.next(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// next reads the next record.
+func (r *recordReader[T]) next() error {
+	var rec T
+	err := r.dec.Decode(&rec)
+	r.rec, r.ok = rec, err == nil
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (r *recordReader[T]) close() error { return r.file.Close() }
+
+// recordWriter writes gob-encoded records to a file.
+type recordWriter[T any] struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *gob.Encoder
+	n    int // number of records written
+}
+
+func newRecordWriter[T any](name string) (*recordWriter[T], error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &recordWriter[T]{file: f, w: w, enc: gob.NewEncoder(w)}, nil
+}
+
+func (w *recordWriter[T]) write(rec *T) error {
+	w.n++
+	return w.enc.Encode(rec)
+}
+
+// close flushes the records written, and closes the file.  It may be
+// called more than once.
+func (w *recordWriter[T]) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.w.Flush()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
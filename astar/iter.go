@@ -0,0 +1,125 @@
+package astar
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// Visit is a state taken off the frontier by a search, in the order
+// reported by Expansions.
+type Visit[S any] struct {
+	State S
+	Cost  int  // cost of the path to State
+	Depth int  // number of steps in the path to State
+	Goal  bool // true if State is a goal state, which is not expanded
+}
+
+// Goals returns an iterator over the paths from start to the goal states
+// of space, found by an A* search that carries on past each goal until
+// the whole space has been explored.  The paths come in order of cost as
+// long as the heuristic is consistent (a heuristic of zero will do), so
+// the first one is the path SearchContext would return.  The caller can
+// stop after the first few, or exhaust the iterator to see every goal.
+//
+// Goal states are not expanded, and each state is reached by its
+// cheapest path only, so a path never passes through another goal, and
+// there is at most one path to each goal state.
+//
+// If the search is stopped by ctx or opts.MaxExpanded, the error is
+// yielded with a zero Solution, and the iteration ends.  Running out of
+// states, or reaching opts.MaxDepth, just ends the iteration.  opts may
+// be nil; its Weight, BeamWidth and Anytime are ignored.  opts.Result is
+// kept up to date as the iteration goes, and describes the last path
+// yielded.
+//
+//	for sol, err := range astar.Goals(ctx, space, start, nil) {
+//		if err != nil {
+//			...
+//		}
+//		fmt.Println(sol.Cost, sol.Path)
+//	}
+func Goals[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) iter.Seq2[Solution[S], error] {
+
+	return func(yield func(Solution[S], error) bool) {
+		e := newEngine(ctx, space, start, exhaustive(opts))
+		defer e.result.finish(e.started)
+		for {
+			n, err := e.next()
+			if err == nil {
+				node := e.tree[n]
+				if !e.space.Done(node.state) {
+					err = e.expand(n)
+				} else {
+					path := e.tree.path(n)
+					bound := e.bound(node.cost)
+					e.result.found(node.cost, node.depth, bound)
+					e.obs.goal(path, node.cost)
+					if !yield(Solution[S]{path, node.cost, bound}, nil) {
+						return
+					}
+					continue
+				}
+			}
+			if err != nil {
+				if !exhausted(err) {
+					yield(Solution[S]{}, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// Expansions returns an iterator over the states taken off the frontier
+// by the same search as Goals, in the order it takes them, for tools that
+// need to watch a search step by step.  Goal states are included, marked
+// as such, but are not expanded; the search carries on past them until
+// the caller stops the iteration or the space is exhausted.  Errors are
+// reported as for Goals.
+func Expansions[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, opts *Options[S]) iter.Seq2[Visit[S], error] {
+
+	return func(yield func(Visit[S], error) bool) {
+		e := newEngine(ctx, space, start, exhaustive(opts))
+		defer e.result.finish(e.started)
+		for {
+			n, err := e.next()
+			if err == nil {
+				node := e.tree[n]
+				goal := e.space.Done(node.state)
+				if !yield(Visit[S]{node.state, node.cost, node.depth, goal}, nil) {
+					return
+				}
+				if goal {
+					continue
+				}
+				err = e.expand(n)
+			}
+			if err != nil {
+				if !exhausted(err) {
+					yield(Visit[S]{}, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// exhaustive returns a copy of opts without the options that trade
+// optimality for speed, for a search that explores the whole space.
+func exhaustive[S any](opts *Options[S]) *Options[S] {
+	o := Options[S]{}
+	if opts != nil {
+		o = *opts
+	}
+	o.Weight, o.BeamWidth, o.Anytime = 0, 0, nil
+	return &o
+}
+
+// exhausted reports whether err means that the search ran out of states
+// to explore.
+func exhausted(err error) bool {
+	return errors.Is(err, ErrNoPath) || errors.Is(err, ErrMaxDepth)
+}
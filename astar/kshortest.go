@@ -0,0 +1,232 @@
+package astar
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// KShortest returns the k cheapest loopless paths from start to a goal
+// state, cheapest first, using Yen's algorithm: each path after the first
+// is found by an A* search that leaves an earlier path at one of its
+// states, and may not return to the states before that one, nor take the
+// same next step as any path found so far with the same beginning.  Two
+// paths are distinct if the keys of their states differ, and a path is
+// loopless if no key appears in it twice.
+//
+// If there are fewer than k such paths, all of them are returned, with a
+// nil error.  But if there is no path at all, KShortest returns no paths,
+// with the error of the first search: ErrNoPath, or ErrMaxDepth, as for
+// SearchContext.  If a later search stops with an error (other than
+// running out of states or reaching opts.MaxDepth), the paths found so
+// far are returned with that error.
+//
+// opts may be nil; its Weight, BeamWidth, Anytime and Checkpoint are
+// ignored.  opts.MaxExpanded limits the states expanded by all the
+// searches together, and opts.Result holds their totals, and describes the
+// last path found.  Observers only see each path found, not the events of
+// the searches.
+func KShortest[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, k int, opts *Options[S]) (solutions []Solution[S], err error) {
+
+	o := exhaustive(opts)
+	result := o.result()
+	defer result.finish(time.Now())
+	obs := observers[S](o.Observers)
+	o.Observers, o.Checkpoint = nil, ""
+
+	y := &yen[S, K]{ctx: ctx, space: space, opts: o, result: result}
+	accepted := []*yenPath[S, K]{}
+	candidates := []*yenPath[S, K]{}
+	for len(accepted) < k {
+		var p *yenPath[S, K]
+		if len(accepted) == 0 {
+			p, err = y.search(start, 0, nil, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			last := accepted[len(accepted)-1]
+			for i := 0; i < len(last.path)-1; i++ {
+				q, err := y.spur(last, i, accepted)
+				if err != nil {
+					return solutions, err
+				}
+				if q != nil && !containsPath(accepted, q) && !containsPath(candidates, q) {
+					candidates = append(candidates, q)
+				}
+			}
+			if len(candidates) == 0 {
+				break
+			}
+			// Take the cheapest candidate, or the first found of those
+			// that cost the same, so that the order is deterministic.
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return candidates[i].cost() < candidates[j].cost()
+			})
+			p, candidates = candidates[0], candidates[1:]
+		}
+		accepted = append(accepted, p)
+		solutions = append(solutions, Solution[S]{p.path, p.cost(), p.bound})
+		result.found(p.cost(), len(p.path)-1, p.bound)
+		obs.goal(p.path, p.cost())
+	}
+	return solutions, nil
+}
+
+// yen holds what the searches of KShortest share.
+type yen[S any, K comparable] struct {
+	ctx    context.Context
+	space  Space[S, K]
+	opts   *Options[S]
+	result *SearchResult
+}
+
+// yenPath is a path found by KShortest, with the keys of its states and
+// the cost of the path up to each of them, and the bound reported by the
+// searches that found it.
+type yenPath[S any, K comparable] struct {
+	path  []S
+	keys  []K
+	costs []int
+	bound float64
+}
+
+func (p *yenPath[S, K]) cost() int { return p.costs[len(p.costs)-1] }
+
+// spur returns the cheapest path that follows p up to its state i, and
+// then leaves every accepted path with the same beginning, or nil if
+// there is none.
+func (y *yen[S, K]) spur(p *yenPath[S, K], i int, accepted []*yenPath[S, K]) (*yenPath[S, K], error) {
+	if y.opts.MaxDepth > 0 && i >= y.opts.MaxDepth {
+		return nil, nil
+	}
+	removed := map[K]bool{}
+	for _, key := range p.keys[:i] {
+		removed[key] = true
+	}
+	steps := map[K]bool{}
+	for _, q := range accepted {
+		if len(q.keys) > i+1 && samePrefix(p.keys, q.keys, i+1) {
+			steps[q.keys[i+1]] = true
+		}
+	}
+	q, err := y.search(p.path[i], i, p, removed, steps)
+	if exhausted(err) {
+		return nil, nil
+	}
+	return q, err
+}
+
+// search runs an A* search from start, which is state i of root (or the
+// start state, if root is nil), that avoids the removed states, and does
+// not take any of the steps from start to the states in steps.  It returns
+// the path from the start state, through root, to the goal it reaches.
+func (y *yen[S, K]) search(start S, i int, root *yenPath[S, K],
+	removed, steps map[K]bool) (*yenPath[S, K], error) {
+
+	opts := *y.opts
+	var result SearchResult
+	opts.Result = &result
+	if opts.MaxDepth > 0 {
+		opts.MaxDepth -= i
+	}
+	if opts.MaxExpanded > 0 {
+		if opts.MaxExpanded -= y.result.Expanded; opts.MaxExpanded <= 0 {
+			return nil, ErrMaxExpanded
+		}
+	}
+	space := &spurSpace[S, K]{y.space, y.space.Key(start), removed, steps}
+	spur, err := SearchContext[S, K](y.ctx, space, start, &opts)
+	y.result.Expanded += result.Expanded
+	y.result.Generated += result.Generated
+	y.result.Duplicates += result.Duplicates
+	y.result.MaxFrontier = max(y.result.MaxFrontier, result.MaxFrontier)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &yenPath[S, K]{costs: []int{0}, bound: result.Bound}
+	if root != nil {
+		p.bound = max(p.bound, root.bound)
+		p.path = append(p.path, root.path[:i]...)
+		p.keys = append(p.keys, root.keys[:i]...)
+		p.costs = append(p.costs[:0], root.costs[:i+1]...)
+	}
+	for j, state := range spur {
+		p.path = append(p.path, state)
+		p.keys = append(p.keys, y.space.Key(state))
+		if j > 0 {
+			p.costs = append(p.costs, p.cost()+stepCost(y.space, spur[j-1], state))
+		}
+	}
+	return p, nil
+}
+
+// spurSpace is a Space without the removed states, and without the steps
+// from the state with key from to the states in steps.
+type spurSpace[S any, K comparable] struct {
+	Space[S, K]
+	from    K
+	removed map[K]bool
+	steps   map[K]bool
+}
+
+func (s *spurSpace[S, K]) Successors(state S) []Successor[S] {
+	all := s.Space.Successors(state)
+	from := s.Key(state) == s.from
+	result := make([]Successor[S], 0, len(all))
+	for _, next := range all {
+		key := s.Key(next.State)
+		if s.removed[key] || (from && s.steps[key]) {
+			continue
+		}
+		result = append(result, next)
+	}
+	return result
+}
+
+// stepCost returns the cost of the cheapest step from state to a state
+// with the key of next.
+func stepCost[S any, K comparable](space Space[S, K], state, next S) int {
+	key := space.Key(next)
+	cost := math.MaxInt
+	for _, succ := range space.Successors(state) {
+		if space.Key(succ.State) == key {
+			cost = min(cost, succ.Cost)
+		}
+	}
+	return cost
+}
+
+// samePrefix reports whether the first n keys of a and b are the same.
+func samePrefix[K comparable](a, b []K, n int) bool {
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPath reports whether one of paths has the same keys as p.
+func containsPath[S any, K comparable](paths []*yenPath[S, K], p *yenPath[S, K]) bool {
+	for _, q := range paths {
+		if len(q.keys) == len(p.keys) && samePrefix(q.keys, p.keys, len(p.keys)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diverge returns the number of states at the start of paths a and b that
+// have the same keys in space: the position in the paths at which they
+// part ways, if they do.
+func Diverge[S any, K comparable](space Space[S, K], a, b []S) int {
+	n := 0
+	for n < len(a) && n < len(b) && space.Key(a[n]) == space.Key(b[n]) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,82 @@
+package astar
+
+// Observer is notified of the events of a search, for debugging or
+// visualizing it.  Observers are called synchronously from the search, so
+// they should be quick.
+type Observer[S any] interface {
+	// Expand is called when state, reached at the given cost, is
+	// expanded.
+	Expand(state S, cost int)
+
+	// Enqueue is called when state, a successor of parent reached at the
+	// given cost, is added to the frontier.
+	Enqueue(state, parent S, cost int)
+
+	// Prune is called when state, a successor of parent reached at the
+	// given cost, is dropped because it has already been reached at no
+	// greater cost.
+	Prune(state, parent S, cost int)
+
+	// Goal is called with the path that the search returns, and its cost.
+	Goal(path []S, cost int)
+}
+
+// Hooks is an Observer made up of callback functions.  Any of them may be
+// nil.
+type Hooks[S any] struct {
+	OnExpand  func(state S, cost int)
+	OnEnqueue func(state, parent S, cost int)
+	OnPrune   func(state, parent S, cost int)
+	OnGoal    func(path []S, cost int)
+}
+
+func (h Hooks[S]) Expand(state S, cost int) {
+	if h.OnExpand != nil {
+		h.OnExpand(state, cost)
+	}
+}
+
+func (h Hooks[S]) Enqueue(state, parent S, cost int) {
+	if h.OnEnqueue != nil {
+		h.OnEnqueue(state, parent, cost)
+	}
+}
+
+func (h Hooks[S]) Prune(state, parent S, cost int) {
+	if h.OnPrune != nil {
+		h.OnPrune(state, parent, cost)
+	}
+}
+
+func (h Hooks[S]) Goal(path []S, cost int) {
+	if h.OnGoal != nil {
+		h.OnGoal(path, cost)
+	}
+}
+
+// observers forwards each event to all of a search's Observers.
+type observers[S any] []Observer[S]
+
+func (obs observers[S]) expand(state S, cost int) {
+	for _, o := range obs {
+		o.Expand(state, cost)
+	}
+}
+
+func (obs observers[S]) enqueue(state, parent S, cost int) {
+	for _, o := range obs {
+		o.Enqueue(state, parent, cost)
+	}
+}
+
+func (obs observers[S]) prune(state, parent S, cost int) {
+	for _, o := range obs {
+		o.Prune(state, parent, cost)
+	}
+}
+
+func (obs observers[S]) goal(path []S, cost int) {
+	for _, o := range obs {
+		o.Goal(path, cost)
+	}
+}
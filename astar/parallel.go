@@ -0,0 +1,319 @@
+package astar
+
+import (
+	"container/heap"
+	"context"
+	"hash/maphash"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// nodeRef locates a node in the search tree of one of the workers of a
+// parallel search.
+type nodeRef struct {
+	worker int
+	node   int
+}
+
+// parallelNode is a node of a worker's search tree.  Its parent may
+// belong to another worker.
+type parallelNode[S any] struct {
+	state  S
+	parent nodeRef // worker -1 for the start state
+	depth  int
+	cost   int
+}
+
+// message carries a generated state to the worker that owns it.  It
+// includes what the owner needs to know about the parent, so that workers
+// never look at each other's trees while the search is running.
+type message[S any, K comparable] struct {
+	state  S
+	key    K
+	cost   int
+	depth  int
+	parent nodeRef
+	from   S // the parent's state, for the observers
+}
+
+// parallelSearch holds the state shared by the workers of a parallel
+// search.  Each worker has its own tree in trees, which the others do not
+// touch until the search is over.  The rest is guarded by mu, apart from
+// the atomic counters.
+type parallelSearch[S any, K comparable] struct {
+	ctx   context.Context
+	space Space[S, K]
+	opts  *Options[S]
+	seed  maphash.Seed
+
+	mu      sync.Mutex
+	wake    *sync.Cond
+	inbox   [][]message[S, K] // messages waiting for each worker
+	trees   [][]parallelNode[S]
+	idle    int // number of workers waiting for messages
+	done    bool
+	err     error
+	goal    nodeRef // node of the best goal state found so far
+	cut     bool    // true if any path was cut off by opts.MaxDepth
+	obsLock sync.Mutex
+
+	best       atomic.Int64 // cost of the best goal state found so far
+	expanded   atomic.Int64
+	generated  atomic.Int64
+	duplicates atomic.Int64
+	frontier   atomic.Int64 // sum of the workers' peak frontier sizes
+}
+
+// ParallelSearch returns the cheapest path from start to a goal state,
+// like SearchContext, using a hash-distributed A* search run by the given
+// number of worker goroutines (or GOMAXPROCS, if workers is not
+// positive).
+//
+// Each state is owned by the worker selected by a hash of its key.  Each
+// worker keeps its own queue and closed set for the states it owns, and
+// sends the successors it generates to their owners over per-worker
+// mailboxes.  When a goal state is reached, the workers carry on until
+// none of them has a state that could lead to a cheaper goal, and no
+// states are in transit between them, so the path is just as cheap as the
+// one SearchContext would return, though it may not be the same path.
+//
+// Observers are called from several goroutines, but never at the same
+// time.  In opts.Result, MaxFrontier is the sum of the largest frontier
+// size of each worker.
+func ParallelSearch[S any, K comparable](ctx context.Context, space Space[S, K],
+	start S, workers int, opts *Options[S]) (path []S, err error) {
+
+	if opts == nil {
+		opts = &Options[S]{}
+	}
+	space = withGoal(space, opts)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	result := opts.result()
+	defer result.finish(time.Now())
+
+	p := &parallelSearch[S, K]{
+		ctx:   ctx,
+		space: space,
+		opts:  opts,
+		seed:  maphash.MakeSeed(),
+		inbox: make([][]message[S, K], workers),
+		trees: make([][]parallelNode[S], workers),
+		goal:  nodeRef{-1, -1},
+	}
+	p.wake = sync.NewCond(&p.mu)
+	p.best.Store(math.MaxInt64)
+
+	key := space.Key(start)
+	p.inbox[p.owner(key)] = []message[S, K]{{state: start, key: key,
+		parent: nodeRef{-1, -1}}}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			p.work(w)
+		}(w)
+	}
+	wg.Wait()
+
+	result.Expanded = int(p.expanded.Load())
+	result.Generated = int(p.generated.Load())
+	result.Duplicates = int(p.duplicates.Load())
+	result.MaxFrontier = int(p.frontier.Load())
+
+	switch {
+	case p.err != nil:
+		err = p.err
+	case p.goal.worker >= 0:
+		path = p.path(p.goal)
+		cost := int(p.best.Load())
+		result.found(cost, len(path)-1, 1)
+		observers[S](opts.Observers).goal(path, cost)
+	case p.cut:
+		err = ErrMaxDepth
+	default:
+		err = ErrNoPath
+	}
+	return
+}
+
+// owner returns the worker that owns the state with the given key.
+func (p *parallelSearch[S, K]) owner(key K) int {
+	return int(maphash.Comparable(p.seed, key) % uint64(len(p.inbox)))
+}
+
+// path returns the states on the path from the start state to n.
+func (p *parallelSearch[S, K]) path(n nodeRef) []S {
+	path := make([]S, p.trees[n.worker][n.node].depth+1)
+	for n.worker >= 0 {
+		node := p.trees[n.worker][n.node]
+		path[node.depth] = node.state
+		n = node.parent
+	}
+	return path
+}
+
+// stop ends the search with the given error, if it has not already ended.
+func (p *parallelSearch[S, K]) stop(err error) {
+	p.mu.Lock()
+	if !p.done {
+		p.done = true
+		p.err = err
+		p.wake.Broadcast()
+	}
+	p.mu.Unlock()
+}
+
+// observe calls fn with the search's observers, one goroutine at a time.
+func (p *parallelSearch[S, K]) observe(fn func(observers[S])) {
+	if len(p.opts.Observers) > 0 {
+		p.obsLock.Lock()
+		fn(p.opts.Observers)
+		p.obsLock.Unlock()
+	}
+}
+
+// work runs worker w until the search is over.
+func (p *parallelSearch[S, K]) work(w int) {
+	space := p.space
+	queue := SearchQueue[S]{}
+	gScore := map[K]int{}
+	closed := map[K]bool{}
+	peak := 0
+	outbox := make([][]message[S, K], len(p.inbox))
+	defer func() { p.frontier.Add(int64(peak)) }()
+
+	for {
+		// Collect the states sent to this worker, waiting for some if
+		// there is nothing else worth doing.
+		p.mu.Lock()
+		for !p.done && len(p.inbox[w]) == 0 && !hasWork(queue, p.best.Load()) {
+			p.idle++
+			if p.idle == len(p.inbox) && p.pending() == 0 {
+				// Every worker is idle and no states are in transit.
+				p.done = true
+				p.wake.Broadcast()
+				break
+			}
+			p.wake.Wait()
+			p.idle--
+		}
+		if p.done {
+			p.mu.Unlock()
+			return
+		}
+		msgs := p.inbox[w]
+		p.inbox[w] = nil
+		p.mu.Unlock()
+
+		for _, msg := range msgs {
+			if best, ok := gScore[msg.key]; ok && best <= msg.cost {
+				p.duplicates.Add(1)
+				if msg.parent.worker >= 0 {
+					p.observe(func(obs observers[S]) {
+						obs.prune(msg.state, msg.from, msg.cost)
+					})
+				}
+				continue
+			}
+			gScore[msg.key] = msg.cost
+			delete(closed, msg.key) // re-open
+			if msg.parent.worker >= 0 {
+				p.observe(func(obs observers[S]) {
+					obs.enqueue(msg.state, msg.from, msg.cost)
+				})
+			}
+			p.trees[w] = append(p.trees[w], parallelNode[S]{msg.state,
+				msg.parent, msg.depth, msg.cost})
+			heap.Push(&queue, &SearchItem[S]{
+				priority: float64(msg.cost + space.Heuristic(msg.state)),
+				cost:     msg.cost,
+				node:     len(p.trees[w]) - 1})
+		}
+		peak = max(peak, queue.Len())
+
+		if err := p.ctx.Err(); err != nil {
+			p.stop(err)
+			return
+		}
+		if !hasWork(queue, p.best.Load()) {
+			continue
+		}
+
+		item := heap.Pop(&queue).(*SearchItem[S])
+		node := p.trees[w][item.node]
+		key := space.Key(node.state)
+		if closed[key] || item.cost > gScore[key] {
+			continue
+		}
+		ref := nodeRef{w, item.node}
+
+		if space.Done(node.state) {
+			p.mu.Lock()
+			if int64(node.cost) < p.best.Load() {
+				p.best.Store(int64(node.cost))
+				p.goal = ref
+			}
+			p.mu.Unlock()
+			continue
+		}
+		if p.opts.MaxDepth > 0 && node.depth >= p.opts.MaxDepth {
+			p.mu.Lock()
+			p.cut = true
+			p.mu.Unlock()
+			continue
+		}
+		if n := p.expanded.Add(1); p.opts.MaxExpanded > 0 && n > int64(p.opts.MaxExpanded) {
+			p.expanded.Add(-1)
+			p.stop(ErrMaxExpanded)
+			return
+		}
+
+		p.observe(func(obs observers[S]) { obs.expand(node.state, node.cost) })
+		closed[key] = true
+		for _, next := range space.Successors(node.state) {
+			p.generated.Add(1)
+			nextKey := space.Key(next.State)
+			owner := p.owner(nextKey)
+			outbox[owner] = append(outbox[owner], message[S, K]{next.State,
+				nextKey, node.cost + next.Cost, node.depth + 1, ref, node.state})
+		}
+
+		// Deliver the successors, including this worker's own, which go
+		// through its inbox like any other.
+		p.mu.Lock()
+		sent := false
+		for owner, msgs := range outbox {
+			if len(msgs) > 0 {
+				p.inbox[owner] = append(p.inbox[owner], msgs...)
+				outbox[owner] = msgs[:0]
+				sent = true
+			}
+		}
+		if sent && p.idle > 0 {
+			p.wake.Broadcast()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// hasWork reports whether queue holds a state that could lead to a goal
+// cheaper than best.
+func hasWork[S any](queue SearchQueue[S], best int64) bool {
+	return queue.Len() > 0 && queue.top().priority < float64(best)
+}
+
+// pending returns the number of states waiting in the workers' inboxes.
+// It must be called with p.mu held.
+func (p *parallelSearch[S, K]) pending() (n int) {
+	for _, msgs := range p.inbox {
+		n += len(msgs)
+	}
+	return
+}
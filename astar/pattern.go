@@ -0,0 +1,143 @@
+package astar
+
+import (
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PatternDB is a heuristic that looks up the cost of reaching a goal from
+// the abstract state that a state maps to.  The abstract space is smaller
+// than the space itself, so that it can be solved exhaustively beforehand,
+// by NewPatternDB.
+//
+// The heuristic is admissible and consistent as long as the abstraction
+// keeps every step: if a state has a successor at some cost, its abstract
+// state must have the successor's abstract state as a successor, at no
+// greater cost, and goal states must map to abstract goals.
+//
+//	pdb, err := astar.NewPatternDB(ctx, abstract, goals, pred, nil)
+//	path, err := astar.Search(astar.WithHeuristic(space, pdb.Heuristic), start)
+type PatternDB[S any, A comparable] struct {
+	Abstract func(S) A
+	cost     map[A]int // cost of reaching a goal from each abstract state
+}
+
+// patternVersion is the version of the format written by PatternDB.Save.
+const patternVersion = 1
+
+// patternFile is what PatternDB.Save writes.
+type patternFile[A comparable] struct {
+	Version int
+	Cost    map[A]int
+}
+
+// NewPatternDB solves the abstract space backward from the goals, using
+// pred to find the abstract states from which a state can be reached, and
+// the cost of each of those steps, and returns the pattern database of the
+// costs found.  opts may be nil; only its MaxExpanded, Result and
+// Observers are used.  Observers see the backward search, in which the
+// "parent" of a state is the state it leads to.
+func NewPatternDB[S any, A comparable](ctx context.Context, abstract func(S) A,
+	goals []A, pred func(A) []Successor[A], opts *Options[A]) (*PatternDB[S, A], error) {
+
+	if opts == nil {
+		opts = &Options[A]{}
+	}
+	result := opts.result()
+	defer result.finish(time.Now())
+	obs := observers[A](opts.Observers)
+
+	f := newFrontier[A, A](pred)
+	for _, goal := range goals {
+		f.add(goal, goal, 0, -1)
+	}
+	result.frontier(f.queue.Len())
+	for f.queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item := heap.Pop(&f.queue).(*SearchItem[A])
+		state := f.tree[item.node].state
+		if f.closed[state] || item.cost > f.gScore[state] {
+			continue
+		}
+		if opts.MaxExpanded > 0 && result.Expanded >= opts.MaxExpanded {
+			return nil, ErrMaxExpanded
+		}
+
+		result.Expanded += 1
+		obs.expand(state, item.cost)
+		f.closed[state] = true
+		for _, next := range f.next(state) {
+			result.Generated += 1
+			cost := item.cost + next.Cost
+			if !f.add(next.State, next.State, cost, item.node) {
+				result.Duplicates += 1
+				obs.prune(next.State, state, cost)
+				continue
+			}
+			obs.enqueue(next.State, state, cost)
+		}
+		result.frontier(f.queue.Len())
+	}
+	return &PatternDB[S, A]{abstract, f.gScore}, nil
+}
+
+// Len returns the number of abstract states in the database.
+func (p *PatternDB[S, A]) Len() int { return len(p.cost) }
+
+// Lookup returns the cost of reaching a goal from the abstract state of s.
+// ok is false if no goal can be reached from it.
+func (p *PatternDB[S, A]) Lookup(s S) (cost int, ok bool) {
+	cost, ok = p.cost[p.Abstract(s)]
+	return
+}
+
+// Heuristic returns the cost of reaching a goal from the abstract state of
+// s, or 0 if no goal can be reached from it.  It can be passed to
+// WithHeuristic.
+func (p *PatternDB[S, A]) Heuristic(s S) int {
+	cost, _ := p.Lookup(s)
+	return cost
+}
+
+// Save writes the database to w with encoding/gob, to be read back by
+// LoadPatternDB.  The abstraction is not saved.
+func (p *PatternDB[S, A]) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(&patternFile[A]{patternVersion, p.cost})
+}
+
+// LoadPatternDB reads a database written by PatternDB.Save, and returns it
+// with the given abstraction, which should be the one it was built with.
+func LoadPatternDB[S any, A comparable](r io.Reader, abstract func(S) A) (*PatternDB[S, A], error) {
+	var f patternFile[A]
+	if err := gob.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("astar: reading pattern database: %w", err)
+	}
+	if f.Version != patternVersion {
+		return nil, fmt.Errorf("astar: reading pattern database: unknown version %d",
+			f.Version)
+	}
+	if f.Cost == nil {
+		f.Cost = map[A]int{}
+	}
+	return &PatternDB[S, A]{abstract, f.Cost}, nil
+}
+
+// heuristicSpace is a Space whose heuristic is given by a function.
+type heuristicSpace[S any, K comparable] struct {
+	Space[S, K]
+	h func(S) int
+}
+
+func (s heuristicSpace[S, K]) Heuristic(state S) int { return s.h(state) }
+
+// WithHeuristic returns a Space like space, whose heuristic is h, such as
+// the Heuristic of a PatternDB.
+func WithHeuristic[S any, K comparable](space Space[S, K], h func(S) int) Space[S, K] {
+	return heuristicSpace[S, K]{space, h}
+}
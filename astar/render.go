@@ -0,0 +1,98 @@
+package astar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Renderer writes the states along a path to w, and returns the first
+// error that occurred while writing.  RenderText, RenderCompact,
+// RenderJSON and RenderDiff are Renderers.
+type Renderer[S fmt.Stringer] func(w io.Writer, path []S) error
+
+// pathWriter writes to w until a write fails, and remembers the error.
+type pathWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *pathWriter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// RenderText writes each state along path in full, under a heading with
+// its step number, and a line to mark the end of the path.
+func RenderText[S fmt.Stringer](w io.Writer, path []S) error {
+	p := &pathWriter{w: w}
+	for step, state := range path {
+		p.printf("\nStep %d\n", step)
+		p.printf("%s\n", state.String())
+	}
+	p.printf("** DONE **\n\n")
+	return p.err
+}
+
+// RenderCompact writes one line for each state along path: its step
+// number, then the lines of its String joined by " | ".
+func RenderCompact[S fmt.Stringer](w io.Writer, path []S) error {
+	p := &pathWriter{w: w}
+	width := len(fmt.Sprint(len(path) - 1))
+	for step, state := range path {
+		p.printf("%*d  %s\n", width, step, strings.ReplaceAll(state.String(), "\n", " | "))
+	}
+	return p.err
+}
+
+// RenderJSON writes path as a JSON array of objects with the step number
+// and String of each state:
+//
+//	[
+//	  {"step": 0, "state": "..."},
+//	  ...
+//	]
+func RenderJSON[S fmt.Stringer](w io.Writer, path []S) error {
+	type step struct {
+		Step  int    `json:"step"`
+		State string `json:"state"`
+	}
+	steps := make([]step, len(path))
+	for i, state := range path {
+		steps[i] = step{i, state.String()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(steps)
+}
+
+// RenderDiff writes the first state along path in full, like RenderText,
+// and then only the lines of each state that differ from the one before,
+// with the old line marked "-" and the new one "+".  A line removed has
+// only a "-", and a line added only a "+".
+func RenderDiff[S fmt.Stringer](w io.Writer, path []S) error {
+	p := &pathWriter{w: w}
+	var prev []string
+	for step, state := range path {
+		lines := strings.Split(state.String(), "\n")
+		p.printf("\nStep %d\n", step)
+		if step == 0 {
+			p.printf("%s\n", strings.Join(lines, "\n"))
+		}
+		for i := 0; step > 0 && i < max(len(lines), len(prev)); i++ {
+			if i < len(lines) && i < len(prev) && lines[i] == prev[i] {
+				continue
+			}
+			if i < len(prev) {
+				p.printf("- %s\n", prev[i])
+			}
+			if i < len(lines) {
+				p.printf("+ %s\n", lines[i])
+			}
+		}
+		prev = lines
+	}
+	return p.err
+}
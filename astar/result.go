@@ -0,0 +1,95 @@
+package astar
+
+import (
+	"math"
+	"time"
+)
+
+// SearchResult reports statistics about a search.  Set Options.Result to
+// have a search fill one in.
+type SearchResult struct {
+	Expanded    int           // number of states expanded
+	Generated   int           // number of successor states generated
+	Duplicates  int           // successors dropped as already seen
+	MaxFrontier int           // largest number of states awaiting expansion
+	Cost        int           // cost of the path found, or -1
+	Depth       int           // number of steps in the path found, or -1
+	Elapsed     time.Duration // wall-clock time taken by the search
+
+	// Bound is the most that the path found can cost relative to the
+	// cheapest path, provided the heuristic is admissible: 1 if the path
+	// is the cheapest, and +Inf if there is no guarantee.
+	Bound float64
+}
+
+// Solution is a path to a goal state, with its cost and the bound on its
+// cost described in SearchResult.
+type Solution[S any] struct {
+	Path  []S
+	Cost  int
+	Bound float64
+}
+
+// result returns the SearchResult to be filled in by a search with these
+// options, cleared and ready for use.
+func (opts *Options[S]) result() *SearchResult {
+	r := opts.Result
+	if r == nil {
+		r = &SearchResult{}
+	}
+	*r = SearchResult{Cost: -1, Depth: -1, Bound: math.Inf(1)}
+	return r
+}
+
+// finish records the time elapsed since the search started.  It is meant
+// to be deferred at the start of the search:
+//
+//	defer result.finish(time.Now())
+func (r *SearchResult) finish(started time.Time) {
+	r.Elapsed = time.Since(started)
+}
+
+// found records the cost, length and bound of the path found.
+func (r *SearchResult) found(cost, depth int, bound float64) {
+	r.Cost = cost
+	r.Depth = depth
+	r.Bound = bound
+}
+
+// frontier records the current size of the frontier.
+func (r *SearchResult) frontier(size int) {
+	r.MaxFrontier = max(r.MaxFrontier, size)
+}
+
+// BranchingFactor returns the effective branching factor of the search:
+// the branching factor b* that a uniform tree of the same depth as the
+// path found would need in order to hold all the generated states, that
+// is Generated + 1 = 1 + b* + b*^2 + ... + b*^Depth.  The closer it is to
+// 1, the better the heuristic.  It returns 0 if no path was found, or the
+// path has no steps.
+func (r *SearchResult) BranchingFactor() float64 {
+	if r.Depth <= 0 {
+		return 0
+	}
+	n := float64(r.Generated + 1)
+	nodes := func(b float64) float64 {
+		total, level := 1.0, 1.0
+		for d := 0; d < r.Depth; d++ {
+			level *= b
+			total += level
+		}
+		return total
+	}
+
+	// nodes is increasing in b, so bisect between 0 and n.
+	lo, hi := 0.0, math.Max(n, 1)
+	for i := 0; i < 100 && hi-lo > 1e-9; i++ {
+		mid := (lo + hi) / 2
+		if nodes(mid) < n {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,56 @@
+package astar
+
+// SearchState is implemented by state types that are searched through the
+// States space, and which identify themselves with a string Hash.
+// AstarNextStates only needs to enumerate the neighboring states; the
+// search takes care of recognizing states it has already seen.
+type SearchState interface {
+	String() string
+	Hash() string
+	AstarNextStates() []SearchState
+	Heuristic() int
+	Done() bool
+}
+
+// WeightedSearchState is implemented by states whose transitions do not
+// all cost the same.  States calls AstarSuccessors instead of
+// AstarNextStates for these states.
+type WeightedSearchState interface {
+	SearchState
+	AstarSuccessors() []Successor[SearchState]
+}
+
+// DistanceSearchState is implemented by states that can estimate the cost
+// of getting to another state, so that they can be searched for any of
+// several targets with Targets or Nearest.
+type DistanceSearchState interface {
+	SearchState
+	Distance(to SearchState) int
+}
+
+// States is a Space of SearchState values, keyed by their Hash:
+//
+//	path, err := astar.Search(astar.States{}, initState)
+type States struct{}
+
+func (States) Key(s SearchState) string    { return s.Hash() }
+func (States) Heuristic(s SearchState) int { return s.Heuristic() }
+func (States) Done(s SearchState) bool     { return s.Done() }
+
+// Successors returns the states reachable from s in one step.  Each step
+// costs 1 unless s is a WeightedSearchState.
+func (States) Successors(s SearchState) []Successor[SearchState] {
+	if ws, ok := s.(WeightedSearchState); ok {
+		return ws.AstarSuccessors()
+	}
+	return UnitCost(s.AstarNextStates())
+}
+
+// Distance returns from.Distance(to) if from is a DistanceSearchState,
+// and 0 otherwise.
+func (States) Distance(from, to SearchState) int {
+	if ds, ok := from.(DistanceSearchState); ok {
+		return ds.Distance(to)
+	}
+	return 0
+}
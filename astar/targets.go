@@ -0,0 +1,96 @@
+package astar
+
+import (
+	"context"
+)
+
+// goalSpace is a Space whose goal states are chosen by a predicate.
+type goalSpace[S any, K comparable] struct {
+	Space[S, K]
+	goal func(S) bool
+}
+
+func (g goalSpace[S, K]) Done(s S) bool { return g.goal(s) }
+
+// withGoal returns space, with its goal states replaced by those chosen by
+// opts.Goal, if it is set.
+func withGoal[S any, K comparable](space Space[S, K], opts *Options[S]) Space[S, K] {
+	if opts.Goal == nil {
+		return space
+	}
+	return goalSpace[S, K]{space, opts.Goal}
+}
+
+// Distancer is implemented by spaces that can estimate the cost of getting
+// from any state to any other, as Targets needs for its heuristic.
+type Distancer[S any] interface {
+	Distance(from, to S) int
+}
+
+// TargetSpace is a Space searched for any of a set of goal states, made by
+// Targets.
+type TargetSpace[S any, K comparable] struct {
+	Space[S, K]
+	goals []S
+	index map[K]int // position in goals of each goal's key
+}
+
+// Targets returns a Space like space, whose goal states are the given
+// ones (or rather, the states with the same keys) instead of those that
+// are Done.  If space is a Distancer, the heuristic is the least distance
+// from a state to any of the goals, which is admissible (or consistent) if
+// the distance to each goal is.  Otherwise, the heuristic is 0.
+func Targets[S any, K comparable](space Space[S, K], goals []S) *TargetSpace[S, K] {
+	t := &TargetSpace[S, K]{space, goals, map[K]int{}}
+	for i := len(goals) - 1; i >= 0; i-- {
+		t.index[space.Key(goals[i])] = i
+	}
+	return t
+}
+
+func (t *TargetSpace[S, K]) Done(s S) bool {
+	_, ok := t.index[t.Key(s)]
+	return ok
+}
+
+func (t *TargetSpace[S, K]) Heuristic(s S) int {
+	d, ok := t.Space.(Distancer[S])
+	if !ok || len(t.goals) == 0 {
+		return 0
+	}
+	h := d.Distance(s, t.goals[0])
+	for _, goal := range t.goals[1:] {
+		h = min(h, d.Distance(s, goal))
+	}
+	return h
+}
+
+// Goal returns the position in the goals of the goal state s, or -1 if s
+// is not a goal.  If several goals share the key of s, it returns the
+// first of them.
+func (t *TargetSpace[S, K]) Goal(s S) int {
+	if i, ok := t.index[t.Key(s)]; ok {
+		return i
+	}
+	return -1
+}
+
+// Nearest returns the cheapest path from start to any of the goals, like
+// SearchContext searching Targets(space, goals), along with the position
+// in goals of the goal reached.  If no goal is reached, it returns -1, and
+// the error from SearchContext.  opts.Goal is ignored.
+func Nearest[S any, K comparable](ctx context.Context, space Space[S, K], start S,
+	goals []S, opts *Options[S]) (path []S, goal int, err error) {
+
+	if opts != nil && opts.Goal != nil {
+		o := *opts
+		o.Goal = nil
+		opts = &o
+	}
+	t := Targets(space, goals)
+	path, err = SearchContext[S, K](ctx, t, start, opts)
+	if err != nil {
+		return nil, -1, err
+	}
+	return path, t.Goal(path[len(path)-1]), nil
+}
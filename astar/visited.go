@@ -0,0 +1,267 @@
+package astar
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// VisitedSet chooses how a search remembers the states it has reached, so
+// that it does not explore them again.  It is one of ExactSet, BloomSet
+// or LRUSet.  Only ExactSet remembers everything, and the memory it uses
+// grows with the number of states reached; the others use a fixed amount
+// of memory, at some cost described with each of them.
+//
+// Whatever the set, the search still keeps a node for every state it
+// queues, to build the path found, so the memory used by a search is only
+// bounded if the frontier is too, as with Options.BeamWidth.
+type VisitedSet interface {
+	isVisitedSet()
+}
+
+// ExactSet remembers the cheapest known cost of every state reached, and
+// whether it has been expanded, in a map.  It is the default.  A search
+// with an ExactSet finds the cheapest path if the heuristic is
+// admissible, and expands each state at most once if it is consistent.
+type ExactSet struct{}
+
+// DefaultBloomCapacity is the number of states a BloomSet is sized for
+// when its Capacity is not positive: about 1.2MB at a 1% rate.
+const DefaultBloomCapacity = 1 << 20
+
+// BloomSet remembers the states that have been expanded in a Bloom
+// filter, a bit array sized for Capacity states (DefaultBloomCapacity if
+// it is not positive) with the given FalsePositiveRate (0.01 if it is
+// zero).  Each state takes about 10 bits at a 1% rate, and 14 bits at
+// 0.1%.
+//
+// The costs of the states are not remembered, so a state may be queued
+// several times before it is expanded, and is never expanded again
+// afterwards.  The path found is therefore only guaranteed to be the
+// cheapest if the heuristic is consistent.  Worse, the filter may report
+// a state that has never been expanded as expanded, and the search then
+// drops it.  That can make the search miss the cheapest path, or fail to
+// find a path at all where one exists.  The chance of that grows quickly
+// once more than Capacity states have been expanded.  The filter hashes
+// with a new random seed for each search, so the states dropped differ
+// from one run to the next.
+type BloomSet struct {
+	Capacity          int
+	FalsePositiveRate float64
+}
+
+// LRUSet remembers the cheapest known cost of the Capacity states most
+// recently reached or expanded, as a transposition table, and forgets the
+// least recently used state when it is full.
+//
+// A forgotten state is treated as new if it is reached again, so it may
+// be queued and expanded again, repeating work that was done before.
+// Forgetting never drops a path, so when a goal can be reached, the
+// search still finds the cheapest path if the heuristic is admissible,
+// though it may take much longer when the table is small compared with
+// the number of states in play.
+//
+// But when no goal can be reached, in a space with cycles (such as one
+// whose steps can be undone, like RTG's) and more reachable states than
+// the Capacity, the search may never run out of states: each forgotten
+// state can be queued again at a higher cost, and each time it takes
+// another node in the search tree, so the search runs until it is out of
+// memory.  If the Capacity is at least the number of reachable states,
+// nothing is forgotten, and the search ends with ErrNoPath.  A search
+// with an LRUSet smaller than the space therefore needs opts.MaxExpanded,
+// or a ctx with a deadline, to be sure of returning.
+type LRUSet struct {
+	Capacity int
+}
+
+func (ExactSet) isVisitedSet() {}
+func (BloomSet) isVisitedSet() {}
+func (LRUSet) isVisitedSet()   {}
+
+// visited is the set in which the engine records the states it has
+// reached.
+type visited[K comparable] interface {
+	// reach records that the state with key k has been reached at cost
+	// g, and reports whether the state should be queued, which it
+	// should unless it is known to have been reached as cheaply before.
+	reach(k K, g int) bool
+
+	// current reports whether the state with key k, taken off the queue
+	// with cost g, should be expanded, which it should unless it is known
+	// to have been expanded, or reached more cheaply, since it was
+	// queued.
+	current(k K, g int) bool
+
+	// expand records that the state with key k has been expanded, having
+	// been reached at cost g.
+	expand(k K, g int)
+}
+
+// newVisited returns an empty set of the kind chosen by v.
+func newVisited[K comparable](v VisitedSet) visited[K] {
+	switch v := v.(type) {
+	case BloomSet:
+		return newBloomSet[K](v.Capacity, v.FalsePositiveRate)
+	case LRUSet:
+		return newLRUSet[K](v.Capacity)
+	default:
+		return newExactSet[K]()
+	}
+}
+
+// exactSet implements ExactSet.  The open set is made up of the states
+// in the queue, and cost holds the cheapest known cost of reaching each
+// state that has been queued.  The closed set holds the states that have
+// been expanded.  A closed state is re-opened if a cheaper path to it
+// turns up later, which can happen when the heuristic is admissible but
+// not consistent.
+type exactSet[K comparable] struct {
+	cost   map[K]int
+	closed map[K]bool
+}
+
+func newExactSet[K comparable]() *exactSet[K] {
+	return &exactSet[K]{cost: map[K]int{}, closed: map[K]bool{}}
+}
+
+func (s *exactSet[K]) reach(k K, g int) bool {
+	if known, ok := s.cost[k]; ok && known <= g {
+		return false
+	}
+	s.cost[k] = g
+	delete(s.closed, k) // re-open
+	return true
+}
+
+func (s *exactSet[K]) current(k K, g int) bool {
+	return !s.closed[k] && g <= s.cost[k]
+}
+
+func (s *exactSet[K]) expand(k K, g int) {
+	s.closed[k] = true
+}
+
+// bloomSet implements BloomSet, using k bit positions for each key,
+// derived from a single hash by double hashing.
+type bloomSet[K comparable] struct {
+	bits []uint64
+	k    int
+	seed maphash.Seed
+}
+
+func newBloomSet[K comparable](capacity int, rate float64) *bloomSet[K] {
+	if rate <= 0 || rate >= 1 {
+		rate = 0.01
+	}
+	if capacity <= 0 {
+		capacity = DefaultBloomCapacity
+	}
+	n := float64(capacity)
+	m := math.Ceil(-n * math.Log(rate) / (math.Ln2 * math.Ln2))
+	k := max(int(math.Round(m/n*math.Ln2)), 1)
+	return &bloomSet[K]{
+		bits: make([]uint64, (int(m)+63)/64),
+		k:    k,
+		seed: maphash.MakeSeed(),
+	}
+}
+
+// positions calls fn with each of the bit positions for key.
+func (s *bloomSet[K]) positions(key K, fn func(i uint64)) {
+	h1 := maphash.Comparable(s.seed, key)
+	h2 := bits.RotateLeft64(h1, 32) | 1
+	m := uint64(len(s.bits)) * 64
+	for i := 0; i < s.k; i++ {
+		fn((h1 + uint64(i)*h2) % m)
+	}
+}
+
+func (s *bloomSet[K]) contains(key K) bool {
+	found := true
+	s.positions(key, func(i uint64) {
+		found = found && s.bits[i/64]&(1<<(i%64)) != 0
+	})
+	return found
+}
+
+func (s *bloomSet[K]) reach(k K, g int) bool   { return !s.contains(k) }
+func (s *bloomSet[K]) current(k K, g int) bool { return !s.contains(k) }
+
+func (s *bloomSet[K]) expand(key K, g int) {
+	s.positions(key, func(i uint64) {
+		s.bits[i/64] |= 1 << (i % 64)
+	})
+}
+
+// lruSet implements LRUSet.  The entries are kept in order of use, most
+// recent first.
+type lruSet[K comparable] struct {
+	capacity int
+	order    *list.List // of *lruEntry[K]
+	entries  map[K]*list.Element
+}
+
+type lruEntry[K comparable] struct {
+	key    K
+	cost   int
+	closed bool
+}
+
+func newLRUSet[K comparable](capacity int) *lruSet[K] {
+	return &lruSet[K]{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		entries:  map[K]*list.Element{},
+	}
+}
+
+// entry returns the entry for key, marked as the most recently used, or
+// nil if the key has been forgotten or never seen.
+func (s *lruSet[K]) entry(key K) *lruEntry[K] {
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K])
+}
+
+// add adds an entry for key, forgetting the least recently used entry if
+// the set is full.
+func (s *lruSet[K]) add(key K, cost int) *lruEntry[K] {
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Back()
+		delete(s.entries, oldest.Value.(*lruEntry[K]).key)
+		s.order.Remove(oldest)
+	}
+	e := &lruEntry[K]{key: key, cost: cost}
+	s.entries[key] = s.order.PushFront(e)
+	return e
+}
+
+func (s *lruSet[K]) reach(k K, g int) bool {
+	e := s.entry(k)
+	if e == nil {
+		s.add(k, g)
+		return true
+	}
+	if e.cost <= g {
+		return false
+	}
+	e.cost, e.closed = g, false // re-open
+	return true
+}
+
+func (s *lruSet[K]) current(k K, g int) bool {
+	e := s.entry(k)
+	return e == nil || (!e.closed && g <= e.cost)
+}
+
+func (s *lruSet[K]) expand(k K, g int) {
+	e := s.entry(k)
+	if e == nil {
+		e = s.add(k, g)
+	}
+	e.closed = true
+}
@@ -1,12 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/tomp/aoc-2016-go/astar"
 	"github.com/tomp/aoc-2016-go/rtg"
+	"os"
 )
 
+var format = flag.String("format", "text",
+	"how to print each path: text, compact, json or diff")
+
+var renderers = map[string]astar.Renderer[*rtg.State]{
+	"text":    astar.RenderText[*rtg.State],
+	"compact": astar.RenderCompact[*rtg.State],
+	"json":    astar.RenderJSON[*rtg.State],
+	"diff":    astar.RenderDiff[*rtg.State],
+}
+
 func main() {
+	flag.Parse()
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
 
 	fmt.Println("## Example")
 
@@ -17,12 +35,14 @@ func main() {
 
 	fmt.Println(state.String())
 
-	path, err := astar.Search(&state)
+	path, err := astar.Search(rtg.Space{}, &state)
 	if err != nil {
 		fmt.Println("ERROR: no solution found")
 		return
 	}
-	astar.PrintPath(path)
+	if err := render(os.Stdout, path); err != nil {
+		panic(err)
+	}
 
 	fmt.Println("\n## Part 1")
 
@@ -32,12 +52,14 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	path, err = astar.Search(&state)
+	path, err = astar.Search(rtg.Space{}, &state)
 	if err != nil {
 		fmt.Println("ERROR: no solution found")
 		return
 	}
-	astar.PrintPath(path)
+	if err := render(os.Stdout, path); err != nil {
+		panic(err)
+	}
 
 	fmt.Println("\n## Part 2")
 
@@ -47,11 +69,13 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	path, err = astar.Search(&state)
+	path, err = astar.Search(rtg.Space{}, &state)
 	if err != nil {
 		fmt.Println("ERROR: no solution found")
 		return
 	}
-	astar.PrintPath(path)
+	if err := render(os.Stdout, path); err != nil {
+		panic(err)
+	}
 
 }
@@ -1,10 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
-	"container/heap"
-	pq "github.com/tomp/aoc-2016-go/pqueue"
+	"github.com/tomp/aoc-2016-go/astar"
 	"strings"
 )
 
@@ -82,37 +82,47 @@ func (h History) Heuristic() int {
 	return (XSIZE - x - 1) + (YSIZE - y - 1)
 }
 
+// vault is the astar.Space of paths through the vault for a given
+// passcode.  Since the doors that are open depend on the whole path taken,
+// each path is a distinct state, and is its own key.
+type vault struct {
+	passcode string
+}
+
+func (v vault) Key(h History) string    { return h.steps }
+func (v vault) Heuristic(h History) int { return h.Heuristic() }
+func (v vault) Done(h History) bool     { return h.Heuristic() == 0 }
+
+func (v vault) Successors(h History) []astar.Successor[History] {
+	states := []History{}
+	for _, door := range openDoors(v.passcode, h) {
+		states = append(states, h.addStep(string(door)))
+	}
+	return astar.UnitCost(states)
+}
+
 func search(passcode string, longest bool) (path string, nstates int) {
-    queue := pq.Queue{}
-	heap.Init(&queue)
-	initState := History{}
-	initScore := 0 + initState.Heuristic()
-	heap.Push(&queue, pq.NewItem(initScore, &initState))
-	longestPath := ""
-	for len(queue) > 0 {
-		nstates += 1
-		item := heap.Pop(&queue).(*(pq.Item))
-		state := *(item.Value.(*History))
-		score := state.Heuristic()
-		if score == 0 {
-			if ! longest {
-				return state.steps, nstates
-			}
-			if len(state.steps) > len(longestPath) {
-				longestPath = state.steps
-			}
-			continue
+	var result astar.SearchResult
+	opts := &astar.Options[History]{Result: &result}
+	if !longest {
+		states, err := astar.SearchContext(context.Background(),
+			vault{passcode}, History{}, opts)
+		if err == nil {
+			path = states[len(states)-1].steps
 		}
-		for _, door := range openDoors(passcode, state) {
-			newState := state.addStep(string(door))
-			newScore := len(state.steps) + newState.Heuristic()
-			heap.Push(&queue, pq.NewItem(newScore, &newState))
+		return path, result.Expanded
+	}
+
+	// The paths to the vault come in order of length, so the last one
+	// is the longest.  If there are none, path will be the empty string.
+	for solution, err := range astar.Goals(context.Background(),
+		vault{passcode}, History{}, opts) {
+		if err != nil {
+			break
 		}
+		path = solution.Path[len(solution.Path)-1].steps
 	}
-	// if we run out of states to explore without having found a
-	// solution, then longestPath will be the empty string.
-	path = longestPath
-	return
+	return path, result.Expanded
 }
 
 func main() {
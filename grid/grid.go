@@ -0,0 +1,249 @@
+// Package grid provides 2D grids of cells, such as the mazes of many
+// puzzles, parsed from text, along with searches over them built on
+// astar.
+//
+// Positions are Points, with x increasing to the right and y increasing
+// downward, so that (0, 0) is the first character of the first line of
+// the text.
+package grid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Point is a position in a grid, or a step between positions.
+type Point struct {
+	X, Y int
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y)
+}
+
+// Add returns the position reached by taking step q from p.
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+// The steps to the neighboring positions.
+var (
+	Up    = Point{0, -1}
+	Down  = Point{0, 1}
+	Left  = Point{-1, 0}
+	Right = Point{1, 0}
+)
+
+// Moves is a set of steps that can be taken from any position.
+type Moves []Point
+
+// Neighbors4 moves to the 4 orthogonal neighbors.
+var Neighbors4 = Moves{Up, Right, Down, Left}
+
+// Neighbors8 moves to the 8 neighbors, including the diagonal ones.
+var Neighbors8 = Moves{Up, {1, -1}, Right, {1, 1}, Down, {-1, 1}, Left, {-1, -1}}
+
+// Diagonal reports whether any of the moves is diagonal.
+func (m Moves) Diagonal() bool {
+	for _, step := range m {
+		if step.X != 0 && step.Y != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Manhattan returns the number of orthogonal steps between p and q.
+func Manhattan(p, q Point) int {
+	return abs(p.X-q.X) + abs(p.Y-q.Y)
+}
+
+// Chebyshev returns the number of steps between p and q when diagonal
+// steps are allowed.
+func Chebyshev(p, q Point) int {
+	return max(abs(p.X-q.X), abs(p.Y-q.Y))
+}
+
+func abs(val int) int {
+	if val < 0 {
+		return -val
+	}
+	return val
+}
+
+// Cell is the content of a position in a grid: the character at that
+// position in the text the grid was parsed from.
+type Cell byte
+
+const (
+	Open Cell = '.'
+	Wall Cell = '#'
+)
+
+// Grid is a rectangle of cells.  IsWall decides which cells cannot be
+// entered; if it is nil, only Wall cells are walls.  Positions outside the
+// grid are walls too.
+type Grid struct {
+	Width, Height int
+	IsWall        func(c Cell) bool
+
+	cells []byte // row by row
+}
+
+// New returns a grid of the given size, with every cell set to fill.
+func New(width, height int, fill Cell) *Grid {
+	g := &Grid{Width: width, Height: height, cells: make([]byte, width*height)}
+	for i := range g.cells {
+		g.cells[i] = byte(fill)
+	}
+	return g
+}
+
+// Parse returns the grid described by text, one row per line.  Blank lines
+// at the start and end are ignored, but every row must be the same width.
+func Parse(text string) (*Grid, error) {
+	text = strings.Trim(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, fmt.Errorf("grid: no rows")
+	}
+	lines := strings.Split(text, "\n")
+	g := New(len(lines[0]), len(lines), Open)
+	for y, line := range lines {
+		if len(line) != g.Width {
+			return nil, fmt.Errorf("grid: row %d has %d cells, expected %d",
+				y+1, len(line), g.Width)
+		}
+		copy(g.cells[y*g.Width:], line)
+	}
+	return g, nil
+}
+
+// In reports whether p lies within the grid.
+func (g *Grid) In(p Point) bool {
+	return p.X >= 0 && p.X < g.Width && p.Y >= 0 && p.Y < g.Height
+}
+
+// At returns the cell at p, or Wall if p is outside the grid.
+func (g *Grid) At(p Point) Cell {
+	if !g.In(p) {
+		return Wall
+	}
+	return Cell(g.cells[p.Y*g.Width+p.X])
+}
+
+// Set sets the cell at p, which must lie within the grid.
+func (g *Grid) Set(p Point, c Cell) {
+	if !g.In(p) {
+		panic(fmt.Sprintf("grid: %v is outside the %dx%d grid", p, g.Width, g.Height))
+	}
+	g.cells[p.Y*g.Width+p.X] = byte(c)
+}
+
+// Wall reports whether p cannot be entered.
+func (g *Grid) Wall(p Point) bool {
+	if !g.In(p) {
+		return true
+	}
+	if g.IsWall == nil {
+		return g.At(p) == Wall
+	}
+	return g.IsWall(g.At(p))
+}
+
+// Find returns the first position, in reading order, that holds c.
+func (g *Grid) Find(c Cell) (p Point, ok bool) {
+	for i, cell := range g.cells {
+		if Cell(cell) == c {
+			return Point{i % g.Width, i / g.Width}, true
+		}
+	}
+	return Point{}, false
+}
+
+// Neighbors returns the positions that can be entered by taking one of
+// the moves from p.
+func (g *Grid) Neighbors(p Point, moves Moves) []Point {
+	result := []Point{}
+	for _, step := range moves {
+		if next := p.Add(step); !g.Wall(next) {
+			result = append(result, next)
+		}
+	}
+	return result
+}
+
+func (g *Grid) String() string {
+	lines := make([]string, g.Height)
+	for y := range lines {
+		lines[y] = string(g.cells[y*g.Width : (y+1)*g.Width])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Distances returns the number of moves needed to reach each position
+// that can be reached from start, including start itself.
+func (g *Grid) Distances(start Point, moves Moves) map[Point]int {
+	return g.explore(start, moves, -1)
+}
+
+// Within returns the positions that can be reached from start in at most
+// n moves, including start itself, in reading order.
+func (g *Grid) Within(start Point, moves Moves, n int) []Point {
+	return sorted(g.explore(start, moves, n))
+}
+
+// Region returns the positions that can be reached from start, including
+// start itself, in reading order.
+func (g *Grid) Region(start Point, moves Moves) []Point {
+	return sorted(g.explore(start, moves, -1))
+}
+
+// Fill sets every position that can be reached from start, including
+// start itself, to c, and returns the number of positions set.
+func (g *Grid) Fill(start Point, moves Moves, c Cell) int {
+	region := g.Region(start, moves)
+	for _, p := range region {
+		g.Set(p, c)
+	}
+	return len(region)
+}
+
+// explore runs a breadth-first search from start, and returns the distance
+// to each position reached within limit moves (or any number of moves, if
+// limit is negative).  If start is a wall, nothing is reached.
+func (g *Grid) explore(start Point, moves Moves, limit int) map[Point]int {
+	dist := map[Point]int{}
+	if g.Wall(start) {
+		return dist
+	}
+	dist[start] = 0
+	for queue := []Point{start}; len(queue) > 0; queue = queue[1:] {
+		p := queue[0]
+		if limit >= 0 && dist[p] >= limit {
+			continue
+		}
+		for _, next := range g.Neighbors(p, moves) {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[p] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return dist
+}
+
+// sorted returns the positions in dist in reading order.
+func sorted(dist map[Point]int) []Point {
+	points := make([]Point, 0, len(dist))
+	for p := range dist {
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+	return points
+}
@@ -0,0 +1,186 @@
+package grid
+
+import (
+	"context"
+	"github.com/tomp/aoc-2016-go/astar"
+	"github.com/tomp/aoc-2016-go/astar/astartest"
+	"testing"
+)
+
+const maze = `
+#########
+#S..#...#
+#.#.#.#.#
+#.#...#G#
+#########
+`
+
+func mustParse(t *testing.T, text string) *Grid {
+	g, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return g
+}
+
+func TestParse(t *testing.T) {
+	g := mustParse(t, maze)
+	if g.Width != 9 || g.Height != 5 {
+		t.Errorf("grid is %dx%d  (expected 9x5)", g.Width, g.Height)
+	}
+	if start, ok := g.Find('S'); !ok || start != (Point{1, 1}) {
+		t.Errorf("S found at %v, %v  (expected (1,1))", start, ok)
+	}
+	if g.At(Point{0, 0}) != Wall || g.At(Point{2, 1}) != Open || g.At(Point{-1, 0}) != Wall {
+		t.Errorf("unexpected cells in\n%s", g)
+	}
+	if g.String() != maze[1:len(maze)-1] {
+		t.Errorf("grid prints as\n%s", g)
+	}
+
+	if _, err := Parse("..\n.\n"); err == nil {
+		t.Errorf("ragged grid parsed without error")
+	}
+	if _, err := Parse("\n"); err == nil {
+		t.Errorf("empty grid parsed without error")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := [...]struct {
+		p, q                 Point
+		manhattan, chebyshev int
+	}{
+		{Point{0, 0}, Point{0, 0}, 0, 0},
+		{Point{1, 2}, Point{4, 6}, 7, 4},
+		{Point{4, 6}, Point{1, 2}, 7, 4},
+		{Point{-3, 0}, Point{3, 1}, 7, 6},
+	}
+	for ncase, item := range cases {
+		if d := Manhattan(item.p, item.q); d != item.manhattan {
+			t.Errorf("[Case %d] Manhattan distance %d  (expected %d)", ncase, d, item.manhattan)
+		}
+		if d := Chebyshev(item.p, item.q); d != item.chebyshev {
+			t.Errorf("[Case %d] Chebyshev distance %d  (expected %d)", ncase, d, item.chebyshev)
+		}
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	g := mustParse(t, maze)
+	g.IsWall = func(c Cell) bool { return c == Wall || c == 'G' }
+	cases := [...]struct {
+		p     Point
+		moves Moves
+		count int
+	}{
+		{Point{1, 1}, Neighbors4, 2},
+		{Point{1, 1}, Neighbors8, 2},
+		{Point{3, 2}, Neighbors4, 2},
+		{Point{3, 2}, Neighbors8, 4},
+		{Point{7, 2}, Neighbors4, 1}, // the goal is a wall now
+	}
+	for ncase, item := range cases {
+		if n := len(g.Neighbors(item.p, item.moves)); n != item.count {
+			t.Errorf("[Case %d] %d neighbors of %v  (expected %d)", ncase, n, item.p, item.count)
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := mustParse(t, maze)
+	start, _ := g.Find('S')
+	goal, _ := g.Find('G')
+	cases := [...]struct {
+		moves  Moves
+		nsteps int
+	}{
+		{Neighbors4, 12},
+		{Neighbors8, 7},
+	}
+	for ncase, item := range cases {
+		m := Maze{Grid: g, Moves: item.moves, Goal: goal}
+		path, err := m.ShortestPath(start)
+		if err != nil {
+			t.Errorf("[Case %d] unexpected error: %v", ncase, err)
+			continue
+		}
+		if nsteps := len(path) - 1; nsteps != item.nsteps {
+			t.Errorf("[Case %d] solved in %d steps  (expected %d)", ncase, nsteps, item.nsteps)
+		}
+
+		// The SearchState adapter finds a path just as short.
+		states, err := astar.Search[astar.SearchState](astar.States{}, m.State(start))
+		if err != nil || len(states) != len(path) {
+			t.Errorf("[Case %d] SearchState path has %d states, error %v  (expected %d)",
+				ncase, len(states), err, len(path))
+		}
+	}
+
+	g.Set(Point{5, 1}, Wall)
+	if _, err := (Maze{Grid: g, Goal: goal}).ShortestPath(start); err != astar.ErrNoPath {
+		t.Errorf("walled-off goal: error %v  (expected %v)", err, astar.ErrNoPath)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	g := mustParse(t, maze)
+	start, _ := g.Find('S')
+	goals := []Point{{7, 3}, {3, 3}, {7, 1}}
+	path, goal, err := astar.Nearest(context.Background(), Maze{Grid: g}, start, goals, nil)
+	if err != nil || goal != 1 || len(path)-1 != 4 {
+		t.Errorf("goal %d in %d steps, error %v  (expected 1 in 4)", goal, len(path)-1, err)
+	}
+
+	// The SearchState adapter is a DistanceSearchState.
+	m := Maze{Grid: g}
+	states := []astar.SearchState{m.State(goals[0]), m.State(goals[1]), m.State(goals[2])}
+	_, goal, err = astar.Nearest[astar.SearchState](context.Background(), astar.States{},
+		m.State(start), states, nil)
+	if err != nil || goal != 1 {
+		t.Errorf("SearchState goal %d, error %v  (expected 1)", goal, err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	g := mustParse(t, maze)
+	start, _ := g.Find('S')
+	goal, _ := g.Find('G')
+	for _, moves := range []Moves{Neighbors4, Neighbors8} {
+		m := Maze{Grid: g, Moves: moves, Goal: goal}
+		astartest.Check(t, m.State(start), 100)
+		astartest.CheckSpace[Point, Point](t, m, start, 100)
+	}
+}
+
+func TestDistances(t *testing.T) {
+	g := mustParse(t, maze)
+	start, _ := g.Find('S')
+	dist := g.Distances(start, Neighbors4)
+	if len(dist) != 15 || dist[start] != 0 || dist[Point{7, 3}] != 12 {
+		t.Errorf("%d cells reached, goal at %d  (expected 15, 12)", len(dist), dist[Point{7, 3}])
+	}
+
+	within := g.Within(start, Neighbors4, 2)
+	expected := []Point{{1, 1}, {2, 1}, {3, 1}, {1, 2}, {1, 3}}
+	if len(within) != len(expected) {
+		t.Fatalf("within 2 steps: %v  (expected %v)", within, expected)
+	}
+	for i := range within {
+		if within[i] != expected[i] {
+			t.Errorf("within 2 steps: %v  (expected %v)", within, expected)
+			break
+		}
+	}
+
+	g.Set(Point{5, 1}, Wall)
+	if n := g.Fill(start, Neighbors4, 'x'); n != 10 {
+		t.Errorf("filled %d cells  (expected 10)", n)
+	}
+	if g.At(Point{3, 3}) != 'x' || g.At(Point{7, 3}) != 'G' {
+		t.Errorf("unexpected fill\n%s", g)
+	}
+	if n := len(g.Region(Point{0, 0}, Neighbors4)); n != 0 {
+		t.Errorf("region of a wall has %d cells  (expected 0)", n)
+	}
+}
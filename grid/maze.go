@@ -0,0 +1,83 @@
+package grid
+
+import (
+	"github.com/tomp/aoc-2016-go/astar"
+)
+
+// Maze is the astar.Space of the positions in a grid, searched for a path
+// to the Goal position.  Each move costs 1.  Moves is Neighbors4 if nil.
+// The heuristic is the Distance to the goal, which is consistent for
+// Neighbors4 and Neighbors8.
+//
+//	maze := grid.Maze{Grid: g, Goal: goal}
+//	path, err := maze.ShortestPath(start)
+type Maze struct {
+	Grid  *Grid
+	Moves Moves
+	Goal  Point
+}
+
+func (m Maze) moves() Moves {
+	if m.Moves == nil {
+		return Neighbors4
+	}
+	return m.Moves
+}
+
+func (m Maze) Key(p Point) Point { return p }
+func (m Maze) Done(p Point) bool { return p == m.Goal }
+
+func (m Maze) Heuristic(p Point) int { return m.Distance(p, m.Goal) }
+
+// Distance returns the least number of moves from p to q, were there no
+// walls in the way.  It makes Maze an astar.Distancer, so that it can be
+// searched for the nearest of several goals with astar.Nearest.
+func (m Maze) Distance(p, q Point) int {
+	if m.moves().Diagonal() {
+		return Chebyshev(p, q)
+	}
+	return Manhattan(p, q)
+}
+
+func (m Maze) Successors(p Point) []astar.Successor[Point] {
+	return astar.UnitCost(m.Grid.Neighbors(p, m.moves()))
+}
+
+// ShortestPath returns the shortest path from start to the goal, as the
+// positions along it.
+func (m Maze) ShortestPath(start Point) ([]Point, error) {
+	return astar.Search[Point, Point](m, start)
+}
+
+// State returns position p of the maze as an astar.SearchState.
+func (m Maze) State(p Point) State {
+	return State{m, p}
+}
+
+// State is a position in a maze, as an astar.SearchState, for use with
+// astar.States.
+type State struct {
+	Maze Maze
+	Point
+}
+
+func (s State) Hash() string   { return s.Point.String() }
+func (s State) Heuristic() int { return s.Maze.Heuristic(s.Point) }
+func (s State) Done() bool     { return s.Maze.Done(s.Point) }
+
+func (s State) AstarNextStates() []astar.SearchState {
+	result := []astar.SearchState{}
+	for _, p := range s.Maze.Grid.Neighbors(s.Point, s.Maze.moves()) {
+		result = append(result, State{s.Maze, p})
+	}
+	return result
+}
+
+// Distance returns the Distance between the positions of s and to, if to
+// is a State.  It makes State an astar.DistanceSearchState.
+func (s State) Distance(to astar.SearchState) int {
+	if t, ok := to.(State); ok {
+		return s.Maze.Distance(s.Point, t.Point)
+	}
+	return 0
+}
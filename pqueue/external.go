@@ -0,0 +1,233 @@
+package pqueue
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// ErrEmpty is returned by ExternalQueue.Pop when the queue is empty.
+var ErrEmpty = errors.New("pqueue: queue is empty")
+
+// maxRuns is the number of runs an ExternalQueue keeps on disk before it
+// merges them into one, so as not to run out of file descriptors.
+const maxRuns = 64
+
+// ExternalQueue is a priority queue of Items, lowest priority first, for
+// queues too large to keep in memory.  Only a set number of items are held
+// in memory; when there are more, they are sorted and spilled to a run in
+// a temporary file, and the runs are merged back as items are popped.  Items
+// of the same priority are popped in the order they were pushed.
+//
+// Item values are written to the runs with encoding/gob, so their types
+// must be registered with gob.Register.  Popped items are new Items, with
+// the same Priority and Value as the ones pushed.  Close removes the
+// temporary files.
+type ExternalQueue struct {
+	dir     string // directory for the runs, or "" for the default
+	runSize int
+
+	mem  memQueue // the items held in memory
+	runs runQueue // the runs on disk, by their first item
+	seq  uint64   // number of items pushed so far
+	n    int      // number of items in the queue
+	err  error    // the first error that left the queue inconsistent
+}
+
+// NewExternalQueue returns an empty ExternalQueue, which holds at most
+// runSize items in memory, and writes its runs to temporary files in dir
+// (or in the default directory for temporary files, if dir is "").
+func NewExternalQueue(dir string, runSize int) *ExternalQueue {
+	if runSize < 1 {
+		runSize = 1
+	}
+	return &ExternalQueue{dir: dir, runSize: runSize}
+}
+
+// runRecord is an item as written to a run.
+type runRecord struct {
+	Priority int
+	Seq      uint64
+	Value    interface{}
+}
+
+func (r *runRecord) before(s *runRecord) bool {
+	if r.Priority != s.Priority {
+		return r.Priority < s.Priority
+	}
+	return r.Seq < s.Seq
+}
+
+// memQueue is a heap of the records held in memory.
+type memQueue []*runRecord
+
+func (q memQueue) Len() int            { return len(q) }
+func (q memQueue) Less(i, j int) bool  { return q[i].before(q[j]) }
+func (q memQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *memQueue) Push(x interface{}) { *q = append(*q, x.(*runRecord)) }
+
+func (q *memQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[0 : n-1]
+	return item
+}
+
+// run is a sorted run of records in a temporary file, being read back.
+type run struct {
+	file *os.File
+	dec  *gob.Decoder
+	head runRecord // the next record of the run
+	left int       // number of records not yet read, including head
+}
+
+// runQueue is a heap of runs, by their heads.
+type runQueue []*run
+
+func (q runQueue) Len() int            { return len(q) }
+func (q runQueue) Less(i, j int) bool  { return q[i].head.before(&q[j].head) }
+func (q runQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *runQueue) Push(x interface{}) { *q = append(*q, x.(*run)) }
+
+func (q *runQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[0 : n-1]
+	return item
+}
+
+// Len returns the number of items in the queue.
+func (q *ExternalQueue) Len() int { return q.n }
+
+// Runs returns the number of runs on disk.
+func (q *ExternalQueue) Runs() int { return len(q.runs) }
+
+// Push adds item to the queue.  If the queue cannot spill its items to
+// disk, it returns the error, and every later call fails with it too.
+func (q *ExternalQueue) Push(item *Item) error {
+	if q.err != nil {
+		return q.err
+	}
+	heap.Push(&q.mem, &runRecord{item.Priority, q.seq, item.Value})
+	q.seq++
+	q.n++
+	if len(q.mem) >= q.runSize {
+		q.err = q.spill()
+	}
+	return q.err
+}
+
+// Pop removes the item with the lowest priority from the queue and
+// returns it.  It returns ErrEmpty if the queue is empty.  If a run cannot
+// be read, it returns the error, and every later call fails with it too.
+func (q *ExternalQueue) Pop() (*Item, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.n == 0 {
+		return nil, ErrEmpty
+	}
+	var rec runRecord
+	if len(q.runs) == 0 || (len(q.mem) > 0 && q.mem[0].before(&q.runs[0].head)) {
+		rec = *heap.Pop(&q.mem).(*runRecord)
+	} else if rec, q.err = q.next(); q.err != nil {
+		return nil, q.err
+	}
+	q.n--
+	return &Item{rec.Priority, rec.Value, -1}, nil
+}
+
+// next takes the head of the first run, and reads the run's next record.
+func (q *ExternalQueue) next() (runRecord, error) {
+	r := q.runs[0]
+	rec := r.head
+	if r.left--; r.left == 0 {
+		heap.Pop(&q.runs)
+		r.file.Close()
+		return rec, os.Remove(r.file.Name())
+	}
+	r.head = runRecord{}
+	if err := r.dec.Decode(&r.head); err != nil {
+		return rec, err
+	}
+	heap.Fix(&q.runs, 0)
+	return rec, nil
+}
+
+// spill writes the records in memory to a new run, and merges the runs
+// if there are too many of them.
+func (q *ExternalQueue) spill() error {
+	records := []*runRecord(q.mem)
+	sort.Slice(records, func(i, j int) bool { return records[i].before(records[j]) })
+	q.mem = nil
+	i := 0
+	if err := q.write(len(records), func() (*runRecord, error) {
+		i++
+		return records[i-1], nil
+	}); err != nil {
+		return err
+	}
+	if len(q.runs) <= maxRuns {
+		return nil
+	}
+
+	// Merge the runs, by writing out everything on disk as one run.
+	n := 0
+	for _, r := range q.runs {
+		n += r.left
+	}
+	return q.write(n, func() (*runRecord, error) {
+		rec, err := q.next()
+		return &rec, err
+	})
+}
+
+// write writes a run of n records, taken in order from next.  If next or
+// the writing fails, the run is removed, and the error returned.
+func (q *ExternalQueue) write(n int, next func() (*runRecord, error)) error {
+	f, err := os.CreateTemp(q.dir, "pqueue-*.run")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for i := 0; i < n && err == nil; i++ {
+		var rec *runRecord
+		if rec, err = next(); err == nil {
+			err = enc.Encode(rec)
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	r := &run{file: f, dec: gob.NewDecoder(bufio.NewReader(f)), left: n}
+	if err == nil {
+		err = r.dec.Decode(&r.head)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	heap.Push(&q.runs, r)
+	return nil
+}
+
+// Close empties the queue, and removes its temporary files.
+func (q *ExternalQueue) Close() error {
+	var errs []error
+	for _, r := range q.runs {
+		errs = append(errs, r.file.Close(), os.Remove(r.file.Name()))
+	}
+	q.runs, q.mem, q.n = nil, nil, 0
+	return errors.Join(errs...)
+}
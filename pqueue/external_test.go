@@ -0,0 +1,141 @@
+package pqueue
+
+import (
+	"encoding/gob"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+func init() {
+	gob.Register(point{})
+}
+
+func TestExternalQueue(t *testing.T) {
+	dir := t.TempDir()
+	q := NewExternalQueue(dir, 10)
+	defer q.Close()
+
+	// Push enough items to spill, and to merge the runs, popping some
+	// along the way.
+	rng := rand.New(rand.NewSource(1))
+	n := 0
+	last := -1
+	pop := func() {
+		item, err := q.Pop()
+		if err != nil {
+			t.Fatalf("unexpected error popping: %v", err)
+		}
+		p := item.Value.(point)
+		if item.Priority != p.X {
+			t.Fatalf("popped priority %d with value %v", item.Priority, p)
+		}
+		n--
+	}
+	for i := 0; i < 2000; i++ {
+		priority := rng.Intn(500)
+		if err := q.Push(NewItem(priority, point{priority, i})); err != nil {
+			t.Fatalf("unexpected error pushing: %v", err)
+		}
+		n++
+		if i%3 == 0 {
+			pop()
+		}
+	}
+	if q.Len() != n || q.Runs() == 0 || q.Runs() > maxRuns {
+		t.Errorf("queue has %d items in %d runs  (expected %d items, 1 to %d runs)",
+			q.Len(), q.Runs(), n, maxRuns)
+	}
+
+	// What is left comes out in order, with ties in the order pushed.
+	lastY := -1
+	for q.Len() > 0 {
+		item, err := q.Pop()
+		if err != nil {
+			t.Fatalf("unexpected error popping: %v", err)
+		}
+		p := item.Value.(point)
+		if item.Priority < last || (item.Priority == last && p.Y < lastY) {
+			t.Fatalf("popped %v after priority %d, pushed %d", p, last, lastY)
+		}
+		last, lastY = item.Priority, p.Y
+	}
+	if _, err := q.Pop(); err != ErrEmpty {
+		t.Errorf("empty queue popped with error %v  (expected %v)", err, ErrEmpty)
+	}
+
+	// The runs have all been removed.
+	if files, err := os.ReadDir(dir); err != nil || len(files) != 0 {
+		t.Errorf("%d files left behind, error %v", len(files), err)
+	}
+}
+
+func TestExternalQueueClose(t *testing.T) {
+	dir := t.TempDir()
+	q := NewExternalQueue(dir, 2)
+	for i := 0; i < 5; i++ {
+		if err := q.Push(NewItem(i, i)); err != nil {
+			t.Fatalf("unexpected error pushing: %v", err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Errorf("unexpected error closing: %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 || q.Len() != 0 {
+		t.Errorf("%d files and %d items left after Close", len(files), q.Len())
+	}
+
+	q = NewExternalQueue(dir+"/missing", 1)
+	if err := q.Push(NewItem(0, 0)); err == nil {
+		t.Errorf("spilled to a missing directory without error")
+	}
+}
+
+func TestExternalQueueMergeError(t *testing.T) {
+	dir := t.TempDir()
+	q := NewExternalQueue(dir, 2)
+	defer q.Close()
+
+	// Fill maxRuns runs of two large items each, then cut each file off
+	// after the part that has already been read, so that merging the
+	// runs fails.
+	value := strings.Repeat("x", 3000)
+	for i := 0; i < 2*maxRuns; i++ {
+		if err := q.Push(NewItem(i, value)); err != nil {
+			t.Fatalf("unexpected error pushing: %v", err)
+		}
+	}
+	files, _ := os.ReadDir(dir)
+	runs := map[string]bool{}
+	for _, file := range files {
+		runs[file.Name()] = true
+		if err := os.Truncate(filepath.Join(dir, file.Name()), 4096); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		q.Push(NewItem(i, value))
+	}
+	if _, err := q.Pop(); err == nil {
+		t.Fatalf("merged truncated runs without error")
+	}
+
+	// Only the run spilled before the merge is new: the failed merge
+	// leaves no run of its own behind.
+	files, _ = os.ReadDir(dir)
+	added := 0
+	for _, file := range files {
+		if !runs[file.Name()] {
+			added++
+		}
+	}
+	if added != 1 {
+		t.Errorf("%d new runs left after the failed merge  (expected 1)", added)
+	}
+}
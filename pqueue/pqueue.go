@@ -13,28 +13,31 @@ func NewItem(priority int, value interface{}) *Item {
 	return &Item{priority, value, 0}
 }
 
-type Queue []*Item
+// HeapQueue is a priority queue of Items, lowest priority first, to be
+// used through container/heap.  It was called Queue before the generic
+// Queue replaced it; new code should use Queue.
+type HeapQueue []*Item
 
-func (q Queue) Len() int { return len(q) }
+func (q HeapQueue) Len() int { return len(q) }
 
-func (q Queue) Less(i, j int) bool {
+func (q HeapQueue) Less(i, j int) bool {
 	return q[i].Priority < q[j].Priority
 }
 
-func (q Queue) Swap(i, j int) {
+func (q HeapQueue) Swap(i, j int) {
 	q[i], q[j] = q[j], q[i]
 	q[i].Index = i
 	q[j].Index = j
 }
 
-func (q *Queue) Push(x interface{}) {
+func (q *HeapQueue) Push(x interface{}) {
 	n := len(*q)
 	item := x.(*Item)
 	item.Index = n
 	*q = append(*q, item)
 }
 
-func (q *Queue) Pop() interface{} {
+func (q *HeapQueue) Pop() interface{} {
 	old := *q
 	n := len(old)
 	item := old[n-1]
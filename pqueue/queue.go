@@ -0,0 +1,113 @@
+package pqueue
+
+// Order chooses which items a Queue gives up first.
+type Order int
+
+const (
+	Min Order = iota // the item with the lowest priority comes first
+	Max              // the item with the highest priority comes first
+)
+
+// Queue is a priority queue of values of type T.  Values of the same
+// priority come out in the order they were pushed.  The zero Queue is an
+// empty Min queue, ready to use.
+//
+//	q := pqueue.New[string](pqueue.Min)
+//	q.Push("start", 0)
+//	for q.Len() > 0 {
+//		state, priority, _ := q.Pop()
+//		...
+//	}
+type Queue[T any] struct {
+	order Order
+	items []entry[T] // a binary heap, with the first item at the root
+	seq   uint64     // number of values pushed so far
+}
+
+// entry is a value in a Queue, with its priority.
+type entry[T any] struct {
+	value    T
+	priority int
+	seq      uint64 // number of values pushed before this one
+}
+
+// New returns an empty Queue, which gives up its values in the given
+// order.
+func New[T any](order Order) *Queue[T] {
+	return &Queue[T]{order: order}
+}
+
+// Len returns the number of values in the queue.
+func (q *Queue[T]) Len() int { return len(q.items) }
+
+// Push adds value to the queue, with the given priority.
+func (q *Queue[T]) Push(value T, priority int) {
+	q.items = append(q.items, entry[T]{value, priority, q.seq})
+	q.seq++
+	q.up(len(q.items) - 1)
+}
+
+// Pop removes the first value from the queue, and returns it with its
+// priority.  ok is false if the queue is empty.
+func (q *Queue[T]) Pop() (value T, priority int, ok bool) {
+	if len(q.items) == 0 {
+		return value, 0, false
+	}
+	first := q.items[0]
+	last := len(q.items) - 1
+	q.items[0] = q.items[last]
+	q.items[last] = entry[T]{} // so that the value can be collected
+	q.items = q.items[:last]
+	q.down(0)
+	return first.value, first.priority, true
+}
+
+// Peek returns the first value in the queue, and its priority, without
+// removing it.  ok is false if the queue is empty.
+func (q *Queue[T]) Peek() (value T, priority int, ok bool) {
+	if len(q.items) == 0 {
+		return value, 0, false
+	}
+	return q.items[0].value, q.items[0].priority, true
+}
+
+// before reports whether item i should come out before item j.
+func (q *Queue[T]) before(i, j int) bool {
+	a, b := &q.items[i], &q.items[j]
+	if a.priority != b.priority {
+		return (a.priority < b.priority) == (q.order == Min)
+	}
+	return a.seq < b.seq
+}
+
+// up moves item i toward the root until its parent comes before it.
+func (q *Queue[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.before(i, parent) {
+			break
+		}
+		q.items[i], q.items[parent] = q.items[parent], q.items[i]
+		i = parent
+	}
+}
+
+// down moves item i away from the root until it comes before its
+// children.
+func (q *Queue[T]) down(i int) {
+	n := len(q.items)
+	for {
+		first := i
+		if left := 2*i + 1; left < n && q.before(left, first) {
+			first = left
+		}
+		if right := 2*i + 2; right < n && q.before(right, first) {
+			first = right
+		}
+		if first == i {
+			return
+		}
+		q.items[i], q.items[first] = q.items[first], q.items[i]
+		i = first
+	}
+}
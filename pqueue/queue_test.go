@@ -0,0 +1,75 @@
+package pqueue
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	type pushed struct {
+		name     string
+		priority int
+	}
+	values := []pushed{{"a", 3}, {"b", 1}, {"c", 4}, {"d", 1}, {"e", 5}, {"f", 9}, {"g", 2}, {"h", 5}}
+	cases := [...]struct {
+		order    Order
+		expected string
+	}{
+		{Min, "bdgacehf"},
+		{Max, "fehcagbd"},
+	}
+	for ncase, item := range cases {
+		q := New[string](item.order)
+		for _, v := range values {
+			q.Push(v.name, v.priority)
+		}
+		if q.Len() != len(values) {
+			t.Errorf("[Case %d] queue has %d values  (expected %d)", ncase, q.Len(), len(values))
+		}
+		if value, _, ok := q.Peek(); !ok || value != item.expected[:1] {
+			t.Errorf("[Case %d] peeked %q, %v  (expected %q)", ncase, value, ok, item.expected[:1])
+		}
+		got := ""
+		for q.Len() > 0 {
+			value, _, ok := q.Pop()
+			if !ok {
+				t.Fatalf("[Case %d] Pop failed with %d values left", ncase, q.Len())
+			}
+			got += value
+		}
+		if got != item.expected {
+			t.Errorf("[Case %d] popped %q  (expected %q)", ncase, got, item.expected)
+		}
+		if _, _, ok := q.Pop(); ok {
+			t.Errorf("[Case %d] empty queue popped a value", ncase)
+		}
+		if _, _, ok := q.Peek(); ok {
+			t.Errorf("[Case %d] empty queue peeked a value", ncase)
+		}
+	}
+}
+
+func TestQueueRandom(t *testing.T) {
+	// The zero Queue is a Min queue.
+	var q Queue[int]
+	rng := rand.New(rand.NewSource(1))
+	priorities := []int{}
+	for i := 0; i < 1000; i++ {
+		p := rng.Intn(100)
+		q.Push(i, p)
+		priorities = append(priorities, p)
+	}
+	sort.Ints(priorities)
+	lastValue := -1
+	for i, expected := range priorities {
+		value, priority, _ := q.Pop()
+		if priority != expected {
+			t.Fatalf("value %d popped with priority %d  (expected %d)", i, priority, expected)
+		}
+		if i > 0 && priority == priorities[i-1] && value < lastValue {
+			t.Fatalf("value %d of priority %d popped after %d", value, priority, lastValue)
+		}
+		lastValue = value
+	}
+}
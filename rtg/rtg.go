@@ -1,6 +1,7 @@
 package rtg
 
 import (
+	"context"
 	"fmt"
 	"github.com/tomp/aoc-2016-go/astar"
 	"sort"
@@ -10,6 +11,9 @@ import (
 
 const NFLOORS int = 4
 
+// MAXISOTOPES is the largest number of isotopes whose states fit in a Key.
+const MAXISOTOPES int = 15
+
 type State struct {
 	elevator  int
 	generator []int
@@ -43,6 +47,11 @@ func InitialState(elevator int, generator []int, chip []int,
 		err = fmt.Errorf("%d chip values supplied for %d isotopes",
 			len(chip), nisotopes)
 	}
+	if nisotopes > MAXISOTOPES {
+		err = fmt.Errorf("%d isotopes supplied, but at most %d are supported",
+			nisotopes, MAXISOTOPES)
+		return
+	}
 	if elevator < 1 || elevator > NFLOORS {
 		err = fmt.Errorf("Elevator cannot be on floor %d", elevator)
 		return
@@ -154,6 +163,30 @@ func (s *State) Heuristic() int {
 	return dist
 }
 
+// Key identifies a State for the astar.Space search.  Like Hash, it
+// ignores the names of the isotopes, so states that differ only by
+// swapping isotopes share the same key.
+type Key uint64
+
+// Key packs the floors of each generator-chip pair into 4 bits, sorts the
+// pairs, and stores the elevator floor in the lowest 2 bits.
+func (s *State) Key() Key {
+	var pairs [MAXISOTOPES]uint8
+	for iso := 0; iso < s.nisotopes; iso++ {
+		pair := uint8((s.generator[iso]-1)<<2 | (s.chip[iso] - 1))
+		i := iso
+		for ; i > 0 && pairs[i-1] > pair; i-- {
+			pairs[i] = pairs[i-1]
+		}
+		pairs[i] = pair
+	}
+	key := Key(0)
+	for iso := 0; iso < s.nisotopes; iso++ {
+		key = key<<4 | Key(pairs[iso])
+	}
+	return key<<2 | Key(s.elevator-1)
+}
+
 func (s *State) Hash() string {
 	parts := []string{}
 	for iso := range s.isotopes {
@@ -165,6 +198,57 @@ func (s *State) Hash() string {
 	return strings.Join(parts, ",")
 }
 
+// MarshalBinary encodes the state as bytes, so that searches of RTG states
+// can be checkpointed by astar.  It implements encoding.BinaryMarshaler.
+func (s *State) MarshalBinary() ([]byte, error) {
+	data := []byte{byte(s.elevator), byte(s.nisotopes)}
+	for iso := 0; iso < s.nisotopes; iso++ {
+		data = append(data, byte(s.generator[iso]), byte(s.chip[iso]))
+	}
+	for _, name := range s.isotopes {
+		if len(name) > 255 {
+			return nil, fmt.Errorf("isotope name %q is too long", name)
+		}
+		data = append(data, byte(len(name)))
+		data = append(data, name...)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a state encoded by MarshalBinary.  It implements
+// encoding.BinaryUnmarshaler.
+func (s *State) UnmarshalBinary(data []byte) error {
+	short := fmt.Errorf("encoded state is too short")
+	if len(data) < 2 {
+		return short
+	}
+	elevator, nisotopes := int(data[0]), int(data[1])
+	data = data[2:]
+	if len(data) < 2*nisotopes {
+		return short
+	}
+	generator := make([]int, nisotopes)
+	chip := make([]int, nisotopes)
+	for iso := 0; iso < nisotopes; iso++ {
+		generator[iso], chip[iso] = int(data[2*iso]), int(data[2*iso+1])
+	}
+	data = data[2*nisotopes:]
+	isotopes := make([]string, nisotopes)
+	for iso := range isotopes {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return short
+		}
+		isotopes[iso] = string(data[1 : 1+data[0]])
+		data = data[1+data[0]:]
+	}
+	state, err := InitialState(elevator, generator, chip, isotopes)
+	if err != nil {
+		return err
+	}
+	*s = state
+	return nil
+}
+
 // Done returns true if all objects are on the top floor
 func (s *State) Done() bool {
 	for iso := 0; iso < s.nisotopes; iso++ {
@@ -175,6 +259,18 @@ func (s *State) Done() bool {
 	return true
 }
 
+// Goal returns the state in which all objects, and the elevator, are on
+// the top floor.
+func (s *State) Goal() *State {
+	generator := make([]int, s.nisotopes)
+	chip := make([]int, s.nisotopes)
+	for iso := 0; iso < s.nisotopes; iso++ {
+		generator[iso] = NFLOORS
+		chip[iso] = NFLOORS
+	}
+	return &State{NFLOORS, generator, chip, s.isotopes, s.nisotopes}
+}
+
 // Fried returns true if any chips would be fried in the current state.
 func (s *State) Fried() bool {
 	for iso := 0; iso < s.nisotopes; iso++ {
@@ -229,26 +325,26 @@ func (s *State) getFloorState(floor int) (fs *floorState) {
 }
 
 // NextStates returns a list of all states we can reach from the current
-// state.
-func (s *State) NextStates(visited map[string]bool) (states []*State) {
+// state without frying any chips.  Moves that lead to equivalent states,
+// with the same Key, are only listed once.
+func (s *State) NextStates() (states []*State) {
 	floor := s.elevator
 	states = []*State{}
+	seen := map[Key]bool{}
+	add := func(snew *State) {
+		if key := snew.Key(); !snew.Fried() && !seen[key] {
+			seen[key] = true
+			states = append(states, snew)
+		}
+	}
 	if floor < NFLOORS {
 		for _, snew := range s.NextStatesOnFloor(floor + 1) {
-			newHash := snew.Hash()
-			if !visited[newHash] && !snew.Fried() {
-				visited[newHash] = true
-				states = append(states, snew)
-			}
+			add(snew)
 		}
 	}
 	if floor > 1 {
 		for _, snew := range s.NextStatesOnFloor(floor - 1) {
-			newHash := snew.Hash()
-			if !visited[newHash] && !snew.Fried() {
-				visited[newHash] = true
-				states = append(states, snew)
-			}
+			add(snew)
 		}
 	}
 	return
@@ -302,10 +398,76 @@ func (s *State) NextStatesOnFloor(newFloor int) (states []*State) {
 // AstarNextStates returns the list of reachable states as a slice of
 // astar.SearchState objects.  This method is required for an rtg.State
 // to satisfy the astar.SearchState interface.
-func (s *State) AstarNextStates(visited map[string]bool) []astar.SearchState {
+func (s *State) AstarNextStates() []astar.SearchState {
 	result := []astar.SearchState{}
-	for _, state := range s.NextStates(visited) {
+	for _, state := range s.NextStates() {
 		result = append(result, state)
 	}
 	return result
 }
+
+// Space is the astar.Space of RTG states, keyed by State.Key.
+type Space struct{}
+
+func (Space) Key(s *State) Key       { return s.Key() }
+func (Space) Heuristic(s *State) int { return s.Heuristic() }
+func (Space) Done(s *State) bool     { return s.Done() }
+
+func (Space) Successors(s *State) []astar.Successor[*State] {
+	return astar.UnitCost(s.NextStates())
+}
+
+// Counts is the abstraction of a State to the number of objects on each
+// floor, and the floor of the elevator, for a pattern database.  It
+// forgets which objects are which, and so whether any chips are fried.
+type Counts struct {
+	Elevator int
+	Objects  [NFLOORS]int // number of objects on floors 1 to NFLOORS
+}
+
+// Counts returns the abstraction of s.
+func (s *State) Counts() Counts {
+	c := Counts{Elevator: s.elevator}
+	for iso := 0; iso < s.nisotopes; iso++ {
+		c.Objects[s.generator[iso]-1] += 1
+		c.Objects[s.chip[iso]-1] += 1
+	}
+	return c
+}
+
+// Successors returns the abstract states reached by taking one or two of
+// the objects on the elevator's floor to the floor above or below.  Every
+// move of a State is one of these, so a pattern database built from them
+// is an admissible heuristic.  Since the moves can be undone, they are
+// also the predecessors of c.
+func (c Counts) Successors() []astar.Successor[Counts] {
+	result := []astar.Successor[Counts]{}
+	for _, floor := range []int{c.Elevator + 1, c.Elevator - 1} {
+		if floor < 1 || floor > NFLOORS {
+			continue
+		}
+		for n := 1; n <= 2 && n <= c.Objects[c.Elevator-1]; n++ {
+			next := c
+			next.Elevator = floor
+			next.Objects[c.Elevator-1] -= n
+			next.Objects[floor-1] += n
+			result = append(result, astar.Successor[Counts]{State: next, Cost: 1})
+		}
+	}
+	return result
+}
+
+// PatternDB returns a pattern database of the Counts of the states with up
+// to nisotopes isotopes, for use as a heuristic:
+//
+//	pdb, err := rtg.PatternDB(ctx, 5)
+//	path, err := astar.Search(astar.WithHeuristic(rtg.Space{}, pdb.Heuristic), start)
+func PatternDB(ctx context.Context, nisotopes int) (*astar.PatternDB[*State, Counts], error) {
+	goals := []Counts{}
+	for n := 1; n <= nisotopes; n++ {
+		goal := Counts{Elevator: NFLOORS}
+		goal.Objects[NFLOORS-1] = 2 * n
+		goals = append(goals, goal)
+	}
+	return astar.NewPatternDB(ctx, (*State).Counts, goals, Counts.Successors, nil)
+}
@@ -1,9 +1,330 @@
 package rtg
 
 import (
+	"context"
+	"errors"
+	"github.com/tomp/aoc-2016-go/astar"
+	"github.com/tomp/aoc-2016-go/astar/astartest"
+	"os"
 	"testing"
 )
 
-func TestNextStates(t *testing.T) {
-	t.Errorf("No tests have been written yet")
+func mustState(t *testing.T, elevator int, generator []int, chip []int) *State {
+	isotopes := []string{"A", "B", "C", "D", "E", "F", "G"}[:len(generator)]
+	s, err := InitialState(elevator, generator, chip, isotopes)
+	if err != nil {
+		t.Fatalf("InitialState: %v", err)
+	}
+	return &s
+}
+
+func TestKey(t *testing.T) {
+	cases := [...]struct {
+		a, b  *State
+		equal bool
+	}{
+		{mustState(t, 1, []int{2, 3}, []int{1, 1}),
+			mustState(t, 1, []int{3, 2}, []int{1, 1}), true},
+		{mustState(t, 1, []int{2, 3}, []int{1, 2}),
+			mustState(t, 1, []int{3, 2}, []int{2, 1}), true},
+		{mustState(t, 1, []int{2, 3}, []int{1, 2}),
+			mustState(t, 1, []int{3, 2}, []int{1, 2}), false},
+		{mustState(t, 1, []int{2, 3}, []int{1, 1}),
+			mustState(t, 2, []int{2, 3}, []int{1, 1}), false},
+		{mustState(t, 4, []int{4, 4}, []int{4, 4}),
+			mustState(t, 4, []int{4, 4}, []int{4, 4}), true},
+	}
+
+	for ncase, item := range cases {
+		if (item.a.Key() == item.b.Key()) != item.equal {
+			t.Errorf("[Case %d] keys %x and %x, equal should be %v", ncase,
+				item.a.Key(), item.b.Key(), item.equal)
+		}
+		if (item.a.Hash() == item.b.Hash()) != item.equal {
+			t.Errorf("[Case %d] hashes %q and %q, equal should be %v", ncase,
+				item.a.Hash(), item.b.Hash(), item.equal)
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	state := mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1})
+	astartest.Check(t, state, 2000)
+	astartest.CheckSpace[*State, Key](t, Space{}, state, 2000)
+}
+
+func TestInitialStateTooManyIsotopes(t *testing.T) {
+	n := MAXISOTOPES + 1
+	floors := make([]int, n)
+	isotopes := make([]string, n)
+	for i := range floors {
+		floors[i] = 1
+		isotopes[i] = string(rune('A' + i))
+	}
+	if _, err := InitialState(1, floors, floors, isotopes); err == nil {
+		t.Errorf("%d isotopes accepted", n)
+	}
+}
+
+func TestSearchExample(t *testing.T) {
+	algorithms := map[string]func(context.Context, astar.Space[*State, Key],
+		*State, *astar.Options[*State]) ([]*State, error){
+		"SearchContext": astar.SearchContext[*State, Key],
+		"BFS":           astar.BFS[*State, Key],
+		"Dijkstra":      astar.Dijkstra[*State, Key],
+		"IDAStar":       astar.IDAStar[*State, Key],
+	}
+
+	for name, search := range algorithms {
+		state := mustState(t, 1, []int{2, 3}, []int{1, 1})
+		path, err := search(context.Background(), Space{}, state, nil)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if nsteps := len(path) - 1; nsteps != 11 {
+			t.Errorf("[%s] example solved in %d steps  (expected 11)", name, nsteps)
+		}
+		if !path[len(path)-1].Done() {
+			t.Errorf("[%s] path does not end in a goal state", name)
+		}
+	}
+}
+
+func TestBidirectional(t *testing.T) {
+	cases := [...]struct {
+		state  *State
+		nsteps int
+	}{
+		{mustState(t, 1, []int{2, 3}, []int{1, 1}), 11},
+		{mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1}), 31},
+	}
+
+	for ncase, item := range cases {
+		path, err := astar.Bidirectional(context.Background(), Space{},
+			item.state, []*State{item.state.Goal()}, nil, nil)
+		if err != nil {
+			t.Errorf("[Case %d] unexpected error: %v", ncase, err)
+			continue
+		}
+		if nsteps := len(path) - 1; nsteps != item.nsteps {
+			t.Errorf("[Case %d] solved in %d steps  (expected %d)", ncase,
+				nsteps, item.nsteps)
+		}
+		if path[0] != item.state || !path[len(path)-1].Done() {
+			t.Errorf("[Case %d] path does not lead from start to goal", ncase)
+		}
+		for i := 1; i < len(path); i++ {
+			if !isNext(path[i-1], path[i]) {
+				t.Errorf("[Case %d] step %d is not a valid move", ncase, i)
+			}
+		}
+	}
+}
+
+// isNext reports whether b can be reached from a in one move.
+func isNext(a, b *State) bool {
+	for _, s := range a.NextStates() {
+		if s.String() == b.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParallelSearch(t *testing.T) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		state := mustState(t, 1, []int{2, 3}, []int{1, 1})
+		serial, err := astar.Search(Space{}, state)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parallel, err := astar.ParallelSearch(context.Background(), Space{},
+			state, workers, nil)
+		if err != nil {
+			t.Errorf("[%d workers] unexpected error: %v", workers, err)
+			continue
+		}
+		if len(parallel) != len(serial) {
+			t.Errorf("[%d workers] solved in %d steps  (expected %d)", workers,
+				len(parallel)-1, len(serial)-1)
+		}
+		if parallel[0] != state || !parallel[len(parallel)-1].Done() {
+			t.Errorf("[%d workers] path does not lead from start to goal", workers)
+		}
+		for i := 1; i < len(parallel); i++ {
+			if !isNext(parallel[i-1], parallel[i]) {
+				t.Errorf("[%d workers] step %d is not a valid move", workers, i)
+			}
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	state := mustState(t, 2, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1})
+	data, err := state.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded State
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.String() != state.String() || decoded.Key() != state.Key() {
+		t.Errorf("decoded state\n%s\n(expected)\n%s", decoded.String(), state.String())
+	}
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("truncated state decoded without error")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	name := t.TempDir() + "/rtg.ckpt"
+	state := mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	hooks := astar.Hooks[*State]{OnExpand: func(s *State, cost int) {
+		if cost >= 10 {
+			cancel()
+		}
+	}}
+	_, err := astar.SearchContext(ctx, Space{}, state, &astar.Options[*State]{
+		Checkpoint: name,
+		Observers:  []astar.Observer[*State]{hooks},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted search returned error %v", err)
+	}
+
+	path, err := astar.Resume[*State, Key](context.Background(), Space{}, name, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nsteps := len(path) - 1; nsteps != 31 {
+		t.Errorf("resumed search solved in %d steps  (expected 31)", nsteps)
+	}
+	if path[0].String() != state.String() || !path[len(path)-1].Done() {
+		t.Errorf("path does not lead from start to goal")
+	}
+}
+
+func TestExternalBFS(t *testing.T) {
+	state := mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1})
+	path, err := astar.ExternalBFS[*State, Key](context.Background(), Space{}, state,
+		t.TempDir(), 500, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nsteps := len(path) - 1; nsteps != 31 {
+		t.Errorf("solved in %d steps  (expected 31)", nsteps)
+	}
+	if path[0].String() != state.String() || !path[len(path)-1].Done() {
+		t.Errorf("path does not lead from start to goal")
+	}
+}
+
+func TestVisitedSets(t *testing.T) {
+	sets := []astar.VisitedSet{
+		astar.ExactSet{},
+		astar.BloomSet{Capacity: 1 << 16, FalsePositiveRate: 0.001},
+		astar.BloomSet{},
+		astar.LRUSet{Capacity: 4000},
+	}
+	for _, visited := range sets {
+		state := mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1})
+		path, err := astar.SearchContext(context.Background(), Space{}, state,
+			&astar.Options[*State]{Visited: visited})
+		if err != nil {
+			t.Errorf("[%#v] unexpected error: %v", visited, err)
+			continue
+		}
+		if nsteps := len(path) - 1; nsteps != 31 {
+			t.Errorf("[%#v] solved in %d steps  (expected 31)", visited, nsteps)
+		}
+	}
+}
+
+func TestKShortest(t *testing.T) {
+	state := mustState(t, 1, []int{2, 3}, []int{1, 1})
+	solutions, err := astar.KShortest[*State, Key](context.Background(), Space{}, state, 20, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(solutions) != 20 {
+		t.Fatalf("found %d paths  (expected 20)", len(solutions))
+	}
+
+	// The example has 14 distinct solutions in 11 steps, which all begin
+	// the same way.
+	optimal, last := 0, 0
+	for i, solution := range solutions {
+		path := solution.Path
+		if solution.Cost < last || path[0].String() != state.String() || !path[len(path)-1].Done() {
+			t.Errorf("path %d costs %d, and does not lead from start to goal", i, solution.Cost)
+		}
+		if solution.Cost == 11 {
+			optimal++
+		}
+		if n := astar.Diverge[*State, Key](Space{}, solutions[0].Path, path); i > 0 && n < 2 {
+			t.Errorf("path %d diverges from the first after %d states", i, n)
+		}
+		last = solution.Cost
+	}
+	if optimal != 14 {
+		t.Errorf("%d paths in 11 steps  (expected 14)", optimal)
+	}
+}
+
+func TestPatternDB(t *testing.T) {
+	pdb, err := PatternDB(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The database is an admissible and consistent heuristic, which finds
+	// the same shortest paths.
+	cases := [...]struct {
+		state  *State
+		nsteps int
+	}{
+		{mustState(t, 1, []int{2, 3}, []int{1, 1}), 11},
+		{mustState(t, 1, []int{1, 3, 3, 1, 1}, []int{2, 3, 3, 2, 1}), 31},
+	}
+	for ncase, item := range cases {
+		if h := pdb.Heuristic(item.state); h == 0 || h > item.nsteps {
+			t.Errorf("[Case %d] heuristic %d  (expected at most %d)", ncase, h, item.nsteps)
+		}
+		space := astar.WithHeuristic[*State, Key](Space{}, pdb.Heuristic)
+		path, err := astar.Search(space, item.state)
+		if err != nil || len(path)-1 != item.nsteps {
+			t.Errorf("[Case %d] solved in %d steps, error %v  (expected %d)",
+				ncase, len(path)-1, err, item.nsteps)
+		}
+		report, err := astar.CheckHeuristic(context.Background(), space, item.state, 2000)
+		if err != nil || !report.Admissible() || !report.Consistent() {
+			t.Errorf("[Case %d] heuristic is not admissible and consistent: %+v, error %v",
+				ncase, report, err)
+		}
+	}
+
+	name := t.TempDir() + "/rtg.pdb"
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pdb.Save(f); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+	f.Close()
+	f, err = os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	loaded, err := astar.LoadPatternDB(f, (*State).Counts)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if loaded.Len() != pdb.Len() {
+		t.Errorf("loaded %d abstract states  (expected %d)", loaded.Len(), pdb.Len())
+	}
 }
@@ -0,0 +1,162 @@
+// Package tsp finds the cheapest route through a set of points of
+// interest in a search space, such as the marked cells of a maze.  Matrix
+// finds the cost of getting from each point to each other with astar, and
+// Solve finds the cheapest order in which to visit them all, with the
+// Held-Karp algorithm.
+package tsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tomp/aoc-2016-go/astar"
+	"github.com/tomp/aoc-2016-go/grid"
+	"math"
+)
+
+// NoPath is the cost in a distance matrix of getting from one point to
+// another that cannot be reached from it.
+const NoPath = -1
+
+// MaxPoints is the largest number of points Solve accepts.  The time and
+// memory it needs grow as 2^n, for n points.
+const MaxPoints = 20
+
+// ErrNoTour is returned by Solve when there is no route through all the
+// points.
+var ErrNoTour = errors.New("tsp: no route visits every point")
+
+// Matrix returns the cost of the cheapest path from each of the points to
+// each other, so that dist[i][j] is the cost of getting from points[i] to
+// points[j], or NoPath if there is no path.  The paths are found by
+// SearchContext, searching the space returned by search for each
+// destination: its Done method should recognize the destination, and its
+// heuristic, if any, should estimate the cost of getting there.  A search
+// is run for every ordered pair of points, so the spaces need not be
+// symmetric.
+func Matrix[S any, K comparable](ctx context.Context, points []S,
+	search func(to S) astar.Space[S, K]) (dist [][]int, err error) {
+
+	dist = make([][]int, len(points))
+	for i, from := range points {
+		dist[i] = make([]int, len(points))
+		for j, to := range points {
+			if i == j {
+				continue
+			}
+			var result astar.SearchResult
+			_, err := astar.SearchContext(ctx, search(to), from,
+				&astar.Options[S]{Result: &result})
+			switch {
+			case errors.Is(err, astar.ErrNoPath):
+				dist[i][j] = NoPath
+			case err != nil:
+				return nil, err
+			default:
+				dist[i][j] = result.Cost
+			}
+		}
+	}
+	return
+}
+
+// MazeMatrix returns the distance matrix of Matrix for the given points of
+// a grid, taking the given moves.
+func MazeMatrix(ctx context.Context, g *grid.Grid, moves grid.Moves,
+	points []grid.Point) ([][]int, error) {
+	return Matrix(ctx, points, func(to grid.Point) astar.Space[grid.Point, grid.Point] {
+		return grid.Maze{Grid: g, Moves: moves, Goal: to}
+	})
+}
+
+// Tour is a route through all the points of a distance matrix.
+type Tour struct {
+	Order []int // the points in the order visited, starting with 0
+	Cost  int   // the total cost of the route
+}
+
+// Solve returns the cheapest route that starts at point 0 of the distance
+// matrix dist, and visits every other point.  If roundTrip is true, the
+// route returns to point 0 at the end, and Order ends with 0 as well.
+// Solve uses the Held-Karp dynamic programming algorithm, which finds the
+// best route in time O(2^n n^2) for n points, so it refuses more than
+// MaxPoints points.
+func Solve(dist [][]int, roundTrip bool) (tour Tour, err error) {
+	n := len(dist)
+	if n == 0 || n > MaxPoints {
+		return tour, fmt.Errorf("tsp: %d points, expected 1 to %d", n, MaxPoints)
+	}
+	for i, row := range dist {
+		if len(row) != n {
+			return tour, fmt.Errorf("tsp: row %d of the matrix has %d costs, expected %d",
+				i, len(row), n)
+		}
+	}
+
+	// cost[set][j] is the cost of the cheapest route that starts at
+	// point 0, visits the points in set, and ends at point j, which is in
+	// set.  Every set includes point 0.  prev[set][j] is the point before
+	// j on that route.
+	const inf = math.MaxInt
+	full := 1<<n - 1
+	cost := make([][]int, full+1)
+	prev := make([][]int, full+1)
+	for set := 1; set <= full; set += 2 {
+		cost[set] = make([]int, n)
+		prev[set] = make([]int, n)
+		for j := range cost[set] {
+			cost[set][j] = inf
+		}
+	}
+	cost[1][0] = 0
+
+	for set := 1; set <= full; set += 2 {
+		for j := 0; j < n; j++ {
+			if cost[set][j] == inf {
+				continue
+			}
+			for k := 1; k < n; k++ {
+				if set&(1<<k) != 0 || dist[j][k] == NoPath {
+					continue
+				}
+				next := set | 1<<k
+				if c := cost[set][j] + dist[j][k]; c < cost[next][k] {
+					cost[next][k] = c
+					prev[next][k] = j
+				}
+			}
+		}
+	}
+
+	// Choose the best last point, then follow the route back from it.
+	best, last := inf, -1
+	for j := 0; j < n; j++ {
+		c := cost[full][j]
+		if c == inf {
+			continue
+		}
+		if roundTrip && j != 0 {
+			if dist[j][0] == NoPath {
+				continue
+			}
+			c += dist[j][0]
+		}
+		if c < best && (n == 1 || j != 0) {
+			best, last = c, j
+		}
+	}
+	if last < 0 {
+		return tour, ErrNoTour
+	}
+
+	tour.Cost = best
+	tour.Order = make([]int, n)
+	for set, j, i := full, last, n-1; i >= 0; i-- {
+		tour.Order[i] = j
+		set, j = set&^(1<<j), prev[set][j]
+	}
+	if roundTrip && n > 1 {
+		tour.Order = append(tour.Order, 0)
+	}
+	return
+}
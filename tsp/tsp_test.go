@@ -0,0 +1,89 @@
+package tsp
+
+import (
+	"context"
+	"github.com/tomp/aoc-2016-go/grid"
+	"reflect"
+	"testing"
+)
+
+const maze = `
+###########
+#0.1.....2#
+#.#######.#
+#4.......3#
+###########
+`
+
+func TestMazeMatrix(t *testing.T) {
+	g, err := grid.Parse(maze)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	points := []grid.Point{}
+	for _, c := range "01234" {
+		p, _ := g.Find(grid.Cell(c))
+		points = append(points, p)
+	}
+	dist, err := MazeMatrix(context.Background(), g, grid.Neighbors4, points)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]int{
+		{0, 2, 8, 10, 2},
+		{2, 0, 6, 8, 4},
+		{8, 6, 0, 2, 10},
+		{10, 8, 2, 0, 8},
+		{2, 4, 10, 8, 0},
+	}
+	if !reflect.DeepEqual(dist, expected) {
+		t.Errorf("distance matrix %v  (expected %v)", dist, expected)
+	}
+
+	g.Set(grid.Point{X: 9, Y: 2}, grid.Wall)
+	g.Set(grid.Point{X: 2, Y: 3}, grid.Wall)
+	dist, err = MazeMatrix(context.Background(), g, grid.Neighbors4, points)
+	if err != nil || dist[0][3] != NoPath || dist[3][2] != NoPath || dist[2][1] != 6 {
+		t.Errorf("walled-off distance matrix %v, error %v", dist, err)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	dist := [][]int{
+		{0, 2, 8, 10, 2},
+		{2, 0, 6, 8, 4},
+		{8, 6, 0, 2, 10},
+		{10, 8, 2, 0, 8},
+		{2, 4, 10, 8, 0},
+	}
+	cases := [...]struct {
+		dist      [][]int
+		roundTrip bool
+		order     []int
+		cost      int
+	}{
+		{dist, false, []int{0, 4, 1, 2, 3}, 14},
+		{dist, true, []int{0, 4, 3, 2, 1, 0}, 20},
+		{[][]int{{0}}, true, []int{0}, 0},
+		{[][]int{{0, 5}, {NoPath, 0}}, false, []int{0, 1}, 5},
+	}
+	for ncase, item := range cases {
+		tour, err := Solve(item.dist, item.roundTrip)
+		if err != nil {
+			t.Errorf("[Case %d] unexpected error: %v", ncase, err)
+			continue
+		}
+		if tour.Cost != item.cost || !reflect.DeepEqual(tour.Order, item.order) {
+			t.Errorf("[Case %d] tour %v costs %d  (expected %v, %d)", ncase,
+				tour.Order, tour.Cost, item.order, item.cost)
+		}
+	}
+
+	// There is no way back from point 1.
+	if _, err := Solve([][]int{{0, 5}, {NoPath, 0}}, true); err != ErrNoTour {
+		t.Errorf("one-way trip: error %v  (expected %v)", err, ErrNoTour)
+	}
+	if _, err := Solve(nil, false); err == nil {
+		t.Errorf("empty matrix solved without error")
+	}
+}